@@ -0,0 +1,158 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Clipboard is somewhere outside the editor to copy text to,
+// and paste text from, so it can go to and from other programs.
+type Clipboard interface {
+	Copy(text string) error
+	Paste() (string, error)
+}
+
+// Terminal puts text on the clipboard of the terminal emulator,
+// which might be on the far end of an SSH connection, with the
+// OSC 52 escape sequence. Not every terminal will answer a query
+// for what's on its clipboard.
+type Terminal struct {
+	// Out is where the escape sequence to copy text goes.
+	Out io.Writer
+	// Ask sends query to the terminal, and returns the
+	// OSC string that the terminal sends back, without
+	// the leading ESC ] and the terminating BEL or ST.
+	Ask func(query string) (string, error)
+}
+
+// The OSC 52 query for what's on the clipboard, and the
+// start of the terminal's answer.
+const (
+	osc52Query  = "\x1b]52;c;?\x07"
+	osc52Answer = "52;"
+)
+
+// Copy sends text to the terminal's clipboard.
+func (t *Terminal) Copy(text string) error {
+	_, err := fmt.Fprintf(t.Out, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// Paste asks the terminal what's on its clipboard.
+func (t *Terminal) Paste() (string, error) {
+	if t.Ask == nil {
+		return "", errors.New("can't ask the terminal")
+	}
+	answer, err := t.Ask(osc52Query)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(answer, osc52Answer) {
+		return "", errors.New("terminal gave an odd answer")
+	}
+	// What's left is "selection;data".
+	answer = answer[len(osc52Answer):]
+	data := answer[strings.IndexByte(answer, ';')+1:]
+	text, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("terminal's clipboard: %s", err)
+	}
+	return string(text), nil
+}
+
+// Command copies and pastes by running helper programs,
+// like xclip, that work with the local windowing system.
+type Command struct {
+	CopyArgs  []string
+	PasteArgs []string
+}
+
+// Copy runs the copy program with text on its standard input.
+func (c *Command) Copy(text string) error {
+	cmd := exec.Command(c.CopyArgs[0], c.CopyArgs[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// Paste runs the paste program and returns what it prints.
+func (c *Command) Paste() (string, error) {
+	out, err := exec.Command(c.PasteArgs[0], c.PasteArgs[1:]...).Output()
+	return string(out), err
+}
+
+// helpers are the programs FindCommand knows about, and the
+// environment variable that says each one has something to talk to.
+var helpers = []struct {
+	env string
+	cmd Command
+}{
+	{"WAYLAND_DISPLAY", Command{[]string{"wl-copy"}, []string{"wl-paste", "--no-newline"}}},
+	{"DISPLAY", Command{[]string{"xclip", "-selection", "clipboard", "-in"}, []string{"xclip", "-selection", "clipboard", "-out"}}},
+	{"DISPLAY", Command{[]string{"xsel", "--clipboard", "--input"}, []string{"xsel", "--clipboard", "--output"}}},
+	{"", Command{[]string{"pbcopy"}, []string{"pbpaste"}}},
+}
+
+// FindCommand looks for a helper program to use, returning
+// nil if there isn't one that can work.
+func FindCommand() *Command {
+	for _, h := range helpers {
+		if h.env != "" && os.Getenv(h.env) == "" {
+			continue
+		}
+		_, err1 := exec.LookPath(h.cmd.CopyArgs[0])
+		_, err2 := exec.LookPath(h.cmd.PasteArgs[0])
+		if err1 == nil && err2 == nil {
+			cmd := h.cmd
+			return &cmd
+		}
+	}
+	return nil
+}
+
+// Fallback copies to all of its clipboards, and pastes from
+// the first one that can.
+type Fallback []Clipboard
+
+// Copy copies text to every clipboard, and only fails if
+// they all do.
+func (f Fallback) Copy(text string) error {
+	err := errors.New("no clipboard")
+	copied := false
+	for _, c := range f {
+		if e := c.Copy(text); e == nil {
+			copied = true
+		} else {
+			err = e
+		}
+	}
+	if copied {
+		return nil
+	}
+	return err
+}
+
+// Paste pastes from the first clipboard that works.
+func (f Fallback) Paste() (string, error) {
+	err := errors.New("no clipboard")
+	for _, c := range f {
+		var text string
+		if text, err = c.Paste(); err == nil {
+			return text, nil
+		}
+	}
+	return "", err
+}
+
+// Default returns the clipboard to use when nothing says otherwise:
+// a local helper program, if there is one, and the terminal.
+func Default(t *Terminal) Clipboard {
+	if c := FindCommand(); c != nil {
+		return Fallback{c, t}
+	}
+	return Fallback{t}
+}
@@ -0,0 +1,344 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoKilo/highlighter"
+	"GoKilo/keyboard"
+	"GoKilo/row"
+)
+
+// Settings hold what the user can set in the config file.
+type Settings struct {
+	TabStop       int
+	ExpandTab     bool
+	QuitTimes     int           // Ctrl-Qs it takes to quit with unsaved changes
+	StatusTimeout time.Duration // how long status messages stay up, 0 for good
+	Colors        highlighter.Colors
+	Keys          []Binding // on top of the editor's own
+	Profile       string    // "kilo", "vi" or "emacs", for those editors' keys
+
+	// filetypes holds settings for files of a single filetype, by
+	// lower case filetype name, as name and value pairs for Set.
+	filetypes map[string][][2]string
+	// where is the file and line being read, for Bindings.
+	where string
+}
+
+// Binding binds a key, or a chord of keys one after the
+// other, to the editor command called Command. An empty
+// Command unbinds the keys.
+type Binding struct {
+	Keys    []int  // key codes, as from keyboard.ReadKey
+	Command string // a command name
+	Where   string // the config file and line number, if it's from one
+}
+
+// Default returns the settings to use when the config
+// file doesn't say otherwise.
+func Default() Settings {
+	return Settings{
+		TabStop:       row.DefaultTabStop,
+		QuitTimes:     3,
+		StatusTimeout: 5 * time.Second,
+		Colors:        highlighter.DefaultColors(),
+		Profile:       "kilo",
+	}
+}
+
+// Path returns the name of the user's config file,
+// $XDG_CONFIG_HOME/kilo/config or ~/.config/kilo/config.
+func Path() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "kilo", "config")
+}
+
+/* The config file is a simple subset of TOML:
+ *
+ *	# comments start with '#'
+ *	tabstop = 4
+ *	statustimeout = 10
+ *	profile = vi
+ *
+ *	[colors]
+ *	comment = "cyan"
+ *	number = 91
+ *
+ *	[filetype.c]
+ *	tabstop = 4
+ *	expandtab = true
+ *
+ *	[keys]
+ *	"Ctrl-X Ctrl-S" = "save"
+ *	Ctrl-W = save
+ *	Ctrl-B = ""
+ *
+ * A [section] line puts its name, and a dot, in front of the names
+ * on the lines after it, so "colors.comment = cyan" at the top of
+ * the file does the same as the comment line above. Values can be
+ * in double quotes, or not, and so can names.
+ */
+
+// Load reads settings from the file named filename. Anything the
+// file doesn't set keeps its default. A missing file is not an
+// error. If some lines are bad, Load still uses the rest, and
+// returns an error saying which line is the first bad one.
+func Load(filename string) (Settings, error) {
+	s := Default()
+	fd, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	defer fd.Close()
+
+	var first error
+	bad := 0
+	section := ""
+	scanner := bufio.NewScanner(fd)
+	for n := 1; scanner.Scan(); n++ {
+		s.where = fmt.Sprintf("%s:%d", filepath.Base(filename), n)
+		if err := s.parseLine(scanner.Text(), &section); err != nil {
+			if bad++; first == nil {
+				first = fmt.Errorf("%s: %s", s.where, err)
+			}
+		}
+	}
+	s.where = ""
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	if bad > 1 {
+		return s, fmt.Errorf("%s (+%d more)", first, bad-1)
+	}
+	return s, first
+}
+
+// parseLine handles one line of a config file, which is in the
+// given section.
+func (s *Settings) parseLine(line string, section *string) error {
+	line = strings.TrimSpace(stripComment(line))
+	if line == "" {
+		return nil
+	}
+	if line[0] == '[' {
+		if line[len(line)-1] != ']' {
+			return fmt.Errorf("want [section], not %q", line)
+		}
+		name := strings.TrimSpace(line[1 : len(line)-1])
+		if err := checkSection(name); err != nil {
+			return err
+		}
+		*section = name
+		return nil
+	}
+	i := strings.IndexByte(line, '=')
+	if line[0] == '"' {
+		// A quoted name can have '=' in it, as in "Alt-=".
+		if end := closingQuote(line); end > 0 {
+			i = end + strings.IndexByte(line[end:], '=')
+		}
+	}
+	if i < 0 {
+		return fmt.Errorf("want name = value, not %q", line)
+	}
+	name, err := unquote(strings.TrimSpace(line[:i]))
+	if err != nil {
+		return err
+	}
+	value, err := unquote(strings.TrimSpace(line[i+1:]))
+	if err != nil {
+		return err
+	}
+	if *section != "" {
+		name = *section + "." + name
+	}
+	return s.Set(name, value)
+}
+
+// unquote takes the quotes off s, if it has them.
+func unquote(s string) (string, error) {
+	if len(s) == 0 || s[0] != '"' {
+		return s, nil
+	}
+	u, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("bad quotes in %s", s)
+	}
+	return u, nil
+}
+
+// closingQuote returns the index of the quote that closes
+// the one at the start of s, or -1 if none does.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment returns line without any comment on the end.
+func stripComment(line string) string {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"' && (i == 0 || line[i-1] != '\\'):
+			quoted = !quoted
+		case c == '#' && !quoted:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func checkSection(name string) error {
+	if name == "colors" || name == "keys" {
+		return nil
+	}
+	if strings.HasPrefix(name, "filetype.") {
+		return checkFiletype(name[len("filetype."):])
+	}
+	return fmt.Errorf("no section called [%s]", name)
+}
+
+func checkFiletype(ft string) error {
+	if highlighter.FindFiletype(ft) == nil {
+		return fmt.Errorf("no filetype called %q", ft)
+	}
+	return nil
+}
+
+// Names are the settings Set knows that aren't in a section.
+var Names = []string{"tabstop", "expandtab", "quittimes", "statustimeout", "profile"}
+
+// Set changes the setting called name to value.
+func (s *Settings) Set(name, value string) error {
+	switch {
+	case name == "tabstop":
+		n, err := number(name, value, 1, 32)
+		if err != nil {
+			return err
+		}
+		s.TabStop = n
+	case name == "expandtab":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expandtab must be true or false, not %q", value)
+		}
+		s.ExpandTab = b
+	case name == "quittimes":
+		n, err := number(name, value, 0, 100)
+		if err != nil {
+			return err
+		}
+		s.QuitTimes = n
+	case name == "statustimeout":
+		n, err := number(name, value, 0, 3600)
+		if err != nil {
+			return err
+		}
+		s.StatusTimeout = time.Duration(n) * time.Second
+	case name == "profile":
+		if value != "kilo" && value != "vi" && value != "emacs" {
+			return fmt.Errorf("profile must be kilo, vi or emacs, not %q", value)
+		}
+		s.Profile = value
+	case strings.HasPrefix(name, "colors."):
+		colors := highlighter.Colors{}
+		for k, v := range s.Colors {
+			colors[k] = v
+		}
+		if err := colors.Set(name[len("colors."):], value); err != nil {
+			return err
+		}
+		s.Colors = colors
+	case strings.HasPrefix(name, "filetype."):
+		return s.setFiletype(name[len("filetype."):], value)
+	case strings.HasPrefix(name, "keys."):
+		keys, err := keyboard.ParseKeys(name[len("keys."):])
+		if err != nil {
+			return err
+		}
+		b := Binding{Keys: keys, Command: value, Where: s.where}
+		s.Keys = append(s.Keys[:len(s.Keys):len(s.Keys)], b)
+	default:
+		return fmt.Errorf("no setting called %q", name)
+	}
+	return nil
+}
+
+// setFiletype handles a setting for a single filetype, with the
+// name "filetype.<filetype>.<setting>" less the first part.
+func (s *Settings) setFiletype(name, value string) error {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return fmt.Errorf("want filetype.<filetype>.<setting>, not filetype.%s", name)
+	}
+	ft, setting := strings.ToLower(name[:i]), name[i+1:]
+	if err := checkFiletype(ft); err != nil {
+		return err
+	}
+	if setting != "tabstop" && setting != "expandtab" {
+		return fmt.Errorf("only tabstop and expandtab can be set per filetype")
+	}
+	// Check the value now, so errors come with the line they're on.
+	var test Settings
+	if err := test.Set(setting, value); err != nil {
+		return err
+	}
+	filetypes := map[string][][2]string{}
+	for k, v := range s.filetypes {
+		filetypes[k] = v
+	}
+	filetypes[ft] = append(append([][2]string(nil), filetypes[ft]...), [2]string{setting, value})
+	s.filetypes = filetypes
+	return nil
+}
+
+// Tabs returns the tab width, and whether to indent with spaces,
+// for files with the given syntax, which may be nil. The settings
+// for the filetype win over the filetype's own tab settings,
+// which win over the settings for all files.
+func (s *Settings) Tabs(syntax *highlighter.Syntax) (tabStop int, expandTab bool) {
+	t := *s
+	if syntax == nil {
+		return t.TabStop, t.ExpandTab
+	}
+	if syntax.TabStop > 0 {
+		t.TabStop, t.ExpandTab = syntax.TabStop, syntax.ExpandTab
+	}
+	for _, set := range s.filetypes[strings.ToLower(syntax.Filetype)] {
+		t.Set(set[0], set[1])
+	}
+	return t.TabStop, t.ExpandTab
+}
+
+// number parses value as the number setting name, which has
+// to be from min to max.
+func number(name, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a number from %d to %d, not %q", name, min, max, value)
+	}
+	return n, nil
+}
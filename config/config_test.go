@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoKilo/highlighter"
+)
+
+// load writes text to a config file, and loads it.
+func load(t *testing.T, text string) (Settings, error) {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(name, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(name)
+}
+
+func TestLoad(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		text  string
+		check func(Settings) bool
+	}{
+		{"empty", "", func(s Settings) bool { return s.TabStop == Default().TabStop }},
+		{"comment", "# tabstop = 2\n", func(s Settings) bool { return s.TabStop == Default().TabStop }},
+		{"number", "tabstop = 4\n", func(s Settings) bool { return s.TabStop == 4 }},
+		{"no spaces", "tabstop=4", func(s Settings) bool { return s.TabStop == 4 }},
+		{"comment after", "tabstop = 4 # four\n", func(s Settings) bool { return s.TabStop == 4 }},
+		{"quoted", `tabstop = "4"`, func(s Settings) bool { return s.TabStop == 4 }},
+		{"bool", "expandtab = true", func(s Settings) bool { return s.ExpandTab }},
+		{"seconds", "statustimeout = 10", func(s Settings) bool { return s.StatusTimeout == 10*time.Second }},
+		{"never", "statustimeout = 0", func(s Settings) bool { return s.StatusTimeout == 0 }},
+		{"quit times", "quittimes = 1", func(s Settings) bool { return s.QuitTimes == 1 }},
+		{"color section", "[colors]\ncomment = \"bright-red\"\n",
+			func(s Settings) bool { return s.Colors["comment"] == 91 }},
+		{"dotted color", "colors.number = 32", func(s Settings) bool { return s.Colors["number"] == 32 }},
+		{"quoted #", "[colors]\nstring = \"green\" # not \"red\"\n",
+			func(s Settings) bool { return s.Colors["string"] == 32 }},
+		{"normal color", "colors.normal = white", func(s Settings) bool { return s.Colors["normal"] == 37 }},
+		{"default color", "colors.keyword1 = default", func(s Settings) bool { return s.Colors["keyword1"] == 39 }},
+		{"filetype section", "[colors]\ncomment = red\n[filetype.go]\ntabstop = 2\n",
+			func(s Settings) bool { return s.Colors["comment"] == 31 && s.TabStop == Default().TabStop }},
+	} {
+		s, err := load(t, tt.text)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if !tt.check(s) {
+			t.Errorf("%s: %q gave %+v", tt.name, tt.text, s)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want string
+	}{
+		{"tabstop", "config:1: want name = value"},
+		{"tabstop = 0", "config:1: "},
+		{"tabstop = four", "config:1: "},
+		{"\n\nexpandtab = maybe", "config:3: "},
+		{"wrap = true", `config:1: no setting called "wrap"`},
+		{"[colours]", "config:1: no section called [colours]"},
+		{"[colors", "config:1: want [section]"},
+		{"[colors]\nkeyword3 = red", `config:2: no highlight type called "keyword3"`},
+		{"colors.comment = pink", `config:1: no color called "pink"`},
+		{"colors.comment = 38", "config:1: color numbers go from 30 to 37, 39 and 90 to 97, not 38"},
+		{`tabstop = "4`, "config:1: bad quotes"},
+		{"[filetype.cobol]", `config:1: no filetype called "cobol"`},
+		{"[filetype.go]\nquittimes = 2", "config:2: only tabstop and expandtab can be set per filetype"},
+		{"tabstop\nwrap = true\ntabstop = 4", "config:1: want name = value, not \"tabstop\" (+1 more)"},
+	} {
+		_, err := load(t, tt.text)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want one starting %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestLoadKeepsGoodLines(t *testing.T) {
+	s, err := load(t, "tabstop\nwrap = true\ntabstop = 4\n")
+	if err == nil {
+		t.Error("no error for the bad lines")
+	}
+	if s.TabStop != 4 {
+		t.Errorf("tabstop = %d, want the 4 from the good line", s.TabStop)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "none"))
+	if err != nil || s.TabStop != Default().TabStop {
+		t.Errorf("Load of a missing file = %+v, %v, want the defaults", s, err)
+	}
+}
+
+func TestTabs(t *testing.T) {
+	s, err := load(t, "tabstop = 4\n[filetype.c]\ntabstop = 2\nexpandtab = true\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, expand := s.Tabs(nil); n != 4 || expand {
+		t.Errorf("Tabs(nil) = %d, %v, want 4, false", n, expand)
+	}
+	if n, expand := s.Tabs(highlighter.FindFiletype("c")); n != 2 || !expand {
+		t.Errorf("Tabs(c) = %d, %v, want 2, true", n, expand)
+	}
+}
@@ -0,0 +1,143 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GoKilo/filemgt"
+	"GoKilo/highlighter"
+	"GoKilo/row"
+)
+
+/*** buffers ***/
+
+// buffer instances hold one file under edit: its lines, and
+// whether it has unsaved changes. Windows show buffers. A buffer
+// remembers the view of the last window that switched away from
+// it, to restore when a window switches back to it.
+type buffer struct {
+	lastView view
+	numRows  int
+	rows     []*row.Row
+	Dirty    bool
+	Filename string
+	Format   filemgt.Format
+	syntax   *highlighter.Syntax
+	undoLog  undoLog
+	stamp    filemgt.Stamp // the file as last read or saved
+	ignored  filemgt.Stamp // a change on disk the user chose to ignore
+	changes  int           // edits so far, to tell when to write the swap file
+	swapped  int           // changes as of the last swap file write
+	noSwap   bool          // another kilo has the file open
+
+	tabStop   int
+	expandTab bool
+}
+
+// NewBuffer adds an empty buffer for file filename, and
+// makes it the current buffer.
+func (e *Editor) NewBuffer(filename string) {
+	b := &buffer{Filename: filename, Format: filemgt.DefaultFormat()}
+	e.filetypeTabs(b)
+	e.buffers = append(e.buffers, b)
+	e.showBuffer(b)
+}
+
+// SwitchBuffer makes buffer number n, counting from 0 in
+// the order the buffers got created, the current buffer.
+func (e *Editor) SwitchBuffer(n int) {
+	if n < 0 || n >= len(e.buffers) {
+		return
+	}
+	e.showBuffer(e.buffers[n])
+}
+
+// showBuffer puts buffer b in the current window.
+func (e *Editor) showBuffer(b *buffer) {
+	if e.buffer == b {
+		return
+	}
+	if e.buffer != nil {
+		e.buffer.lastView = e.view
+	}
+	e.buffer = b
+	e.view = b.lastView
+}
+
+func (e *Editor) bufferIndex() int {
+	for i, b := range e.buffers {
+		if b == e.buffer {
+			return i
+		}
+	}
+	return -1
+}
+
+// cycleBuffer moves dir buffers forward (or backward, if
+// dir is negative) through the list of buffers.
+func (e *Editor) cycleBuffer(dir int) {
+	n := len(e.buffers)
+	e.SwitchBuffer(((e.bufferIndex()+dir)%n + n) % n)
+	e.SetStatusMessage("Buffer %d of %d: %s", e.bufferIndex()+1, n, e.bufferName())
+}
+
+func (e *Editor) bufferName() string {
+	if e.Filename == "" {
+		return "[No Name]"
+	}
+	return e.Filename
+}
+
+// bufferList describes all the buffers on one line, marking
+// the ones with unsaved changes with a '+'.
+func (e *Editor) bufferList() string {
+	var names []string
+	for i, b := range e.buffers {
+		name := b.Filename
+		if name == "" {
+			name = "[No Name]"
+		}
+		if b.Dirty {
+			name += "+"
+		}
+		names = append(names, fmt.Sprintf("%d:%s", i+1, name))
+	}
+	return strings.Join(names, " ")
+}
+
+// pickBuffer lists the buffers, and switches to the one the
+// user chooses, either by number or by part of its file name.
+func (e *Editor) pickBuffer() {
+	list := strings.ReplaceAll(e.bufferList(), "%", "%%")
+	choice, err := e.prompt(list+" | Buffer: %s", lineOptions{history: "buffer", complete: e.completeBuffer})
+	if err != nil || choice == "" {
+		return
+	}
+	if n, err := strconv.Atoi(choice); err == nil {
+		if n < 1 || n > len(e.buffers) {
+			e.SetStatusMessage("No buffer %d", n)
+			return
+		}
+		e.SwitchBuffer(n - 1)
+		return
+	}
+	for i, b := range e.buffers {
+		if strings.Contains(b.Filename, choice) {
+			e.SwitchBuffer(i)
+			return
+		}
+	}
+	e.SetStatusMessage("No buffer matches %q", choice)
+}
+
+// dirtyBuffers counts the buffers with unsaved changes.
+func (e *Editor) dirtyBuffers() int {
+	n := 0
+	for _, b := range e.buffers {
+		if b.Dirty {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,64 @@
+package editor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"GoKilo/keyboard"
+	"GoKilo/row"
+)
+
+/*** system clipboard ***/
+
+// askTerminal sends query to the terminal and waits for the
+// terminal to answer with an OSC string. Keys typed in the
+// meantime get dropped.
+func (e *Editor) askTerminal(query string) (string, error) {
+	if _, err := io.WriteString(os.Stdout, query); err != nil {
+		return "", err
+	}
+	for {
+		c, err := keyboard.ReadKey()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case keyboard.OSC:
+			return keyboard.OSCString(), nil
+		case keyboard.IDLE:
+			return "", errors.New("terminal didn't answer")
+		case keyboard.RESIZE:
+			e.resize()
+		}
+	}
+}
+
+// exportClipboard copies the editor's clipboard to the system's.
+func (e *Editor) exportClipboard() error {
+	if e.system == nil {
+		return nil
+	}
+	lines := make([]string, len(e.clipboard))
+	for i, line := range e.clipboard {
+		lines[i] = string(row.Bytes(line))
+	}
+	return e.system.Copy(strings.Join(lines, "\n"))
+}
+
+// pasteSystem inserts the text on the system clipboard at the cursor.
+func (e *Editor) pasteSystem() {
+	if e.system == nil {
+		e.SetStatusMessage("No system clipboard")
+		return
+	}
+	e.SetStatusMessage("Reading the system clipboard...")
+	e.RefreshScreen()
+	text, err := e.system.Paste()
+	if err != nil {
+		e.SetStatusMessage("Can't paste: %s", err)
+		return
+	}
+	e.pasteText(text)
+}
@@ -0,0 +1,361 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"GoKilo/highlighter"
+	"GoKilo/keyboard"
+	"GoKilo/row"
+)
+
+/*** command line ***/
+
+// historySize is how many lines each prompt's history keeps.
+const historySize = 100
+
+// A completer finishes the word before the cursor when the user
+// hits Tab at a prompt. It gets the text before the cursor, and
+// returns where in it the word starts, and what the word could be.
+type completer func(before string) (start int, words []string)
+
+// lineOptions say how readLine works for one kind of prompt.
+type lineOptions struct {
+	history    string    // the history to keep lines in, if any
+	complete   completer // finishes words on Tab, if not nil
+	allowEmpty bool      // Enter works on an empty line
+	arrows     bool      // Up and Down go to callback, not the history
+
+	// callback, if not nil, gets called with the line after every
+	// key that changes it, and with every key readLine doesn't use
+	// itself, including Enter and ESC.
+	callback func(line []rune, key int)
+}
+
+// lineEditor holds the line being typed at a prompt.
+type lineEditor struct {
+	buf []rune
+	pos int // the cursor, as an index into buf
+	col int // the cursor's screen column on the message bar
+
+	lines []string // the history, oldest first
+	at    int      // the history line showing, or len(lines) for a new one
+	typed []rune   // the new line, while an old one is showing
+
+	words []string // the words Tab goes through, after the first Tab
+	word  int      // the one showing, or -1 for none
+	start int      // where in buf the word starts
+}
+
+// insert puts rs in at the cursor.
+func (l *lineEditor) insert(rs []rune) {
+	buf := append([]rune{}, l.buf[:l.pos]...)
+	buf = append(buf, rs...)
+	l.buf = append(buf, l.buf[l.pos:]...)
+	l.pos += len(rs)
+}
+
+// deleteTo deletes from the cursor to to, on either side of it.
+func (l *lineEditor) deleteTo(to int) bool {
+	from := l.pos
+	if to < from {
+		from, to = to, from
+	}
+	if from == to {
+		return false
+	}
+	l.buf = append(l.buf[:from:from], l.buf[to:]...)
+	l.pos = from
+	return true
+}
+
+// wordLeft returns the start of the word before the cursor.
+func (l *lineEditor) wordLeft() int {
+	i := l.pos
+	for i > 0 && highlighter.IsSeparator(l.buf[i-1]) {
+		i--
+	}
+	for i > 0 && !highlighter.IsSeparator(l.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordRight returns the end of the word after the cursor.
+func (l *lineEditor) wordRight() int {
+	i := l.pos
+	for i < len(l.buf) && highlighter.IsSeparator(l.buf[i]) {
+		i++
+	}
+	for i < len(l.buf) && !highlighter.IsSeparator(l.buf[i]) {
+		i++
+	}
+	return i
+}
+
+// browse shows the line dir lines away in the history, keeping
+// any new line to come back to. It returns false if there are no
+// more lines that way.
+func (l *lineEditor) browse(dir int) bool {
+	to := l.at + dir
+	if to < 0 || to > len(l.lines) {
+		return false
+	}
+	if l.at == len(l.lines) {
+		l.typed = l.buf
+	}
+	l.at = to
+	if to == len(l.lines) {
+		l.buf = l.typed
+	} else {
+		l.buf = []rune(l.lines[to])
+	}
+	l.pos = len(l.buf)
+	return true
+}
+
+// complete finishes the word before the cursor. The first Tab
+// fills in as much as all the possible words share; after that,
+// each Tab shows the next of them.
+func (l *lineEditor) complete(f completer) bool {
+	if len(l.words) > 0 {
+		l.word = (l.word + 1) % len(l.words)
+		l.replaceWord(l.words[l.word])
+		return true
+	}
+	before := string(l.buf[:l.pos])
+	start, words := f(before)
+	if len(words) == 0 {
+		return false
+	}
+	l.start = utf8.RuneCountInString(before[:start])
+	if len(words) == 1 {
+		l.replaceWord(words[0])
+		return true
+	}
+	l.words, l.word = words, -1
+	common := []rune(words[0])
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, string(common)) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(string(common)) <= len(before)-start {
+		return false
+	}
+	l.replaceWord(string(common))
+	return true
+}
+
+// replaceWord puts w in place of the word the cursor is at the
+// end of.
+func (l *lineEditor) replaceWord(w string) {
+	l.deleteTo(l.start)
+	l.insert([]rune(w))
+}
+
+// hint lists the words Tab goes through, if there are any.
+func (l *lineEditor) hint() string {
+	if len(l.words) == 0 {
+		return ""
+	}
+	return "  [" + strings.Join(l.words, " ") + "]"
+}
+
+// promptMark stands for the cursor in the text a prompt func
+// returns. Nobody can type it.
+const promptMark = "\x00"
+
+// showLine puts the prompt on the message bar, with the cursor where
+// it is in the line.
+func (e *Editor) showLine(prompt func(string) string, l *lineEditor) {
+	msg := prompt(string(l.buf[:l.pos]) + promptMark + string(l.buf[l.pos:]))
+	i := strings.Index(msg, promptMark)
+	if i < 0 {
+		i = len(msg)
+	} else {
+		msg = msg[:i] + msg[i+len(promptMark):]
+	}
+	l.col = 0
+	for _, r := range msg[:i] {
+		l.col += row.RuneWidth(r)
+	}
+	l.col = clamp(l.col, 0, e.termCols-1)
+	e.SetStatusMessage("%s", msg+l.hint())
+}
+
+// remember adds line to the end of the history called name,
+// dropping it from anywhere further back.
+func (e *Editor) remember(name, line string) {
+	if name == "" || line == "" {
+		return
+	}
+	if e.histories == nil {
+		e.histories = map[string][]string{}
+	}
+	var lines []string
+	for _, h := range e.histories[name] {
+		if h != line {
+			lines = append(lines, h)
+		}
+	}
+	lines = append(lines, line)
+	if len(lines) > historySize {
+		lines = lines[len(lines)-historySize:]
+	}
+	e.histories[name] = lines
+}
+
+// readLine reads a line at a prompt, asking prompt for the text
+// to show every time the line changes. It returns false if the
+// user hit ESC.
+func (e *Editor) readLine(prompt func(string) string, opt lineOptions) (string, bool, error) {
+	l := &lineEditor{lines: e.histories[opt.history]}
+	l.at = len(l.lines)
+	e.line = l
+	defer func() { e.line = nil }()
+
+	for {
+		e.showLine(prompt, l)
+		e.RefreshScreen()
+
+		c, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+
+		changed, used := false, true
+		if c != '\t' {
+			l.words = nil
+		}
+		switch c {
+		case keyboard.ARROW_LEFT, keyboard.CTRL_B:
+			l.pos = clamp(l.pos-1, 0, len(l.buf))
+		case keyboard.ARROW_RIGHT, keyboard.CTRL_F:
+			l.pos = clamp(l.pos+1, 0, len(l.buf))
+		case keyboard.CTRL_LEFT:
+			l.pos = l.wordLeft()
+		case keyboard.CTRL_RIGHT:
+			l.pos = l.wordRight()
+		case keyboard.HOME_KEY, keyboard.CTRL_A:
+			l.pos = 0
+		case keyboard.END_KEY, keyboard.CTRL_E:
+			l.pos = len(l.buf)
+		case keyboard.BACKSPACE, keyboard.CTRL_H:
+			changed = l.deleteTo(clamp(l.pos-1, 0, len(l.buf)))
+		case keyboard.DEL_KEY, keyboard.CTRL_D:
+			changed = l.deleteTo(clamp(l.pos+1, 0, len(l.buf)))
+		case keyboard.CTRL_W, keyboard.ALT_BACKSPACE:
+			changed = l.deleteTo(l.wordLeft())
+		case keyboard.CTRL_DEL:
+			changed = l.deleteTo(l.wordRight())
+		case keyboard.CTRL_U:
+			changed = l.deleteTo(0)
+		case keyboard.CTRL_K:
+			changed = l.deleteTo(len(l.buf))
+		case keyboard.ARROW_UP, keyboard.ARROW_DOWN:
+			if opt.arrows {
+				used = false
+				break
+			}
+			dir := 1
+			if c == keyboard.ARROW_UP {
+				dir = -1
+			}
+			changed = l.browse(dir)
+		case keyboard.CTRL_P:
+			changed = l.browse(-1)
+		case keyboard.CTRL_N:
+			changed = l.browse(1)
+		case '\t':
+			if opt.complete != nil {
+				changed = l.complete(opt.complete)
+			}
+		case keyboard.ESCAPE:
+			e.SetStatusMessage("")
+			if opt.callback != nil {
+				opt.callback(l.buf, c)
+			}
+			return "", false, nil
+		case '\r':
+			if len(l.buf) != 0 || opt.allowEmpty {
+				e.SetStatusMessage("")
+				if opt.callback != nil {
+					opt.callback(l.buf, c)
+				}
+				e.remember(opt.history, string(l.buf))
+				return string(l.buf), true, nil
+			}
+		case keyboard.PASTE:
+			var rs []rune
+			for _, r := range keyboard.PastedText() {
+				if r == '\n' {
+					break
+				}
+				if unicode.IsPrint(r) {
+					rs = append(rs, r)
+				}
+			}
+			l.insert(rs)
+			changed = len(rs) > 0
+		default:
+			if c < 0 || c > utf8.MaxRune || !unicode.IsPrint(rune(c)) {
+				used = false
+				break
+			}
+			l.insert([]rune{rune(c)})
+			changed = true
+		}
+		if opt.callback != nil && (changed || !used) {
+			opt.callback(l.buf, c)
+		}
+	}
+}
+
+// fileNames returns the names of the files whose paths start with
+// prefix, with a slash on the end for directories. Names starting
+// with a dot only come up if prefix asks for them.
+func fileNames(prefix string) []string {
+	dir, base := filepath.Split(prefix)
+	read := dir
+	if read == "" {
+		read = "."
+	}
+	entries, err := os.ReadDir(read)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, ent := range entries {
+		name := ent.Name()
+		if !strings.HasPrefix(name, base) || (name[0] == '.' && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if ent.IsDir() {
+			name += string(filepath.Separator)
+		}
+		names = append(names, dir+name)
+	}
+	return names
+}
+
+// completeFile completes a prompt that wants a file name.
+func completeFile(before string) (int, []string) {
+	return 0, fileNames(before)
+}
+
+// completeBuffer completes a prompt that wants a buffer's name.
+func (e *Editor) completeBuffer(before string) (int, []string) {
+	var names []string
+	for _, b := range e.buffers {
+		if b.Filename != "" && strings.HasPrefix(b.Filename, before) {
+			names = append(names, b.Filename)
+		}
+	}
+	sort.Strings(names)
+	return 0, names
+}
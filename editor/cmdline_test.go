@@ -0,0 +1,26 @@
+package editor
+
+import "testing"
+
+func TestComplete(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		words []string
+		want  string
+		ok    bool
+	}{
+		{"e ", []string{"edit"}, "e edit", true},
+		{"e ca", []string{"cat", "car"}, "e ca", false},
+		{"e c", []string{"cat", "car"}, "e ca", true},
+		{"e c", []string{"café1", "cafè2"}, "e caf", true},
+		{"e ", []string{"日本1", "日本2"}, "e 日本", true},
+		{"e ", []string{"日本1", "日曜2"}, "e 日", true},
+	} {
+		l := &lineEditor{buf: []rune(tt.line), pos: len([]rune(tt.line))}
+		f := func(before string) (int, []string) { return len("e "), tt.words }
+		ok := l.complete(f)
+		if got := string(l.buf); got != tt.want || ok != tt.ok {
+			t.Errorf("completing %q from %q = %q, %v, want %q, %v", tt.line, tt.words, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package editor
+
+import (
+	"GoKilo/keyboard"
+)
+
+/*** commands ***/
+
+// A command is something the editor can do when the user presses
+// a key bound to it. run returns false when it's time to quit.
+type command struct {
+	name    string
+	help    string
+	run     func(e *Editor) (bool, error)
+	selects bool // works on the selection made with shifted keys
+	kills   bool // puts text on the kill ring, after any from the last kill
+}
+
+// do turns a function that can't make the editor quit or
+// fail into a command's run function.
+func do(f func(e *Editor)) func(e *Editor) (bool, error) {
+	return func(e *Editor) (bool, error) {
+		f(e)
+		return true, nil
+	}
+}
+
+// commands are all the commands, in the order the help lists them.
+var commands []*command
+
+// The help command lists the commands, so the list has to
+// be made at run time, to keep it from depending on itself.
+func init() {
+	commands = []*command{
+		{name: "save", help: "Save the file", run: (*Editor).saveFile},
+		{name: "quit", help: "Quit, if nothing is unsaved", run: (*Editor).processQuit},
+		{name: "help", help: "List the key bindings", run: do((*Editor).showKeys)},
+		{name: "find", help: "Search for text", run: do(find)},
+		{name: "replace", help: "Find and replace text", run: do((*Editor).replace)},
+		{name: "goto", help: "Go to a line", run: do((*Editor).gotoPrompt)},
+		{name: "command", help: "Type a command, like w, e FILE, goto 40 or set tabstop=4", run: (*Editor).exPrompt},
+		{name: "undo", help: "Undo the last change", run: do((*Editor).undo)},
+		{name: "redo", help: "Redo what undo undid", run: do((*Editor).redo)},
+		{name: "newline", help: "Break the line at the cursor", run: do((*Editor).insertNewLine)},
+		{name: "delete-back", help: "Delete the character before the cursor", run: do(func(e *Editor) {
+			e.deleteSomething(keyboard.BACKSPACE)
+		})},
+		{name: "delete", help: "Delete the character at the cursor", run: do(func(e *Editor) {
+			e.deleteSomething(keyboard.DEL_KEY)
+		})},
+		{name: "delete-word-back", help: "Delete the word before the cursor", run: do(func(e *Editor) {
+			e.deleteWord(-1)
+		})},
+		{name: "delete-word", help: "Delete the word after the cursor", run: do(func(e *Editor) {
+			e.deleteWord(1)
+		})},
+		{name: "left", help: "Move left", run: do(func(e *Editor) {
+			e.moveCursor(keyboard.ARROW_LEFT)
+		})},
+		{name: "right", help: "Move right", run: do(func(e *Editor) {
+			e.moveCursor(keyboard.ARROW_RIGHT)
+		})},
+		{name: "up", help: "Move up", run: do(func(e *Editor) {
+			e.moveCursor(keyboard.ARROW_UP)
+		})},
+		{name: "down", help: "Move down", run: do(func(e *Editor) {
+			e.moveCursor(keyboard.ARROW_DOWN)
+		})},
+		{name: "word-left", help: "Move to the start of a word", run: do(func(e *Editor) {
+			e.moveWord(-1)
+		})},
+		{name: "word-right", help: "Move to the end of a word", run: do(func(e *Editor) {
+			e.moveWord(1)
+		})},
+		{name: "line-start", help: "Move to the start of the line", run: do((*Editor).lineStart)},
+		{name: "line-end", help: "Move to the end of the line", run: do((*Editor).lineEnd)},
+		{name: "page-up", help: "Move up a screenful", run: do(func(e *Editor) {
+			e.moveScreenful(keyboard.PAGE_UP)
+		})},
+		{name: "page-down", help: "Move down a screenful", run: do(func(e *Editor) {
+			e.moveScreenful(keyboard.PAGE_DOWN)
+		})},
+		{name: "file-start", help: "Move to the start of the file", run: do(func(e *Editor) {
+			e.cx, e.cy = 0, 0
+		})},
+		{name: "file-end", help: "Move to the end of the file", run: do((*Editor).moveToEnd)},
+		{name: "select-left", help: "Select to the left", selects: true, run: do(func(e *Editor) {
+			e.shiftMove(keyboard.SHIFT_LEFT)
+		})},
+		{name: "select-right", help: "Select to the right", selects: true, run: do(func(e *Editor) {
+			e.shiftMove(keyboard.SHIFT_RIGHT)
+		})},
+		{name: "select-up", help: "Select up", selects: true, run: do(func(e *Editor) {
+			e.shiftMove(keyboard.SHIFT_UP)
+		})},
+		{name: "select-down", help: "Select down", selects: true, run: do(func(e *Editor) {
+			e.shiftMove(keyboard.SHIFT_DOWN)
+		})},
+		{name: "select-line-start", help: "Select to the start of the line", selects: true, run: do(func(e *Editor) {
+			e.shiftMove(keyboard.SHIFT_HOME)
+		})},
+		{name: "select-line-end", help: "Select to the end of the line", selects: true, run: do(func(e *Editor) {
+			e.shiftMove(keyboard.SHIFT_END)
+		})},
+		{name: "mark", help: "Start selecting at the cursor", run: do((*Editor).setMark)},
+		{name: "copy", help: "Copy the selection", selects: true, run: do((*Editor).copySelection)},
+		{name: "cut", help: "Cut the selection", selects: true, kills: true, run: do((*Editor).cutSelection)},
+		{name: "paste", help: "Paste what was cut or copied", run: do((*Editor).paste)},
+		{name: "kill-line", help: "Cut the rest of the line", kills: true, run: do((*Editor).killLine)},
+		{name: "kill-word", help: "Cut the word after the cursor", kills: true, run: do(func(e *Editor) {
+			e.killWord(1)
+		})},
+		{name: "kill-word-back", help: "Cut the word before the cursor", kills: true, run: do(func(e *Editor) {
+			e.killWord(-1)
+		})},
+		{name: "yank", help: "Paste the newest cut", run: do((*Editor).yank)},
+		{name: "yank-pop", help: "Swap what yank pasted for an older cut", run: do((*Editor).yankPop)},
+		{name: "cancel", help: "Drop the selection", run: do((*Editor).cancel)},
+		{name: "paste-system", help: "Paste from the system clipboard", run: do((*Editor).pasteSystem)},
+		{name: "next-buffer", help: "Show the next buffer", run: do(func(e *Editor) {
+			e.cycleBuffer(1)
+		})},
+		{name: "prev-buffer", help: "Show the previous buffer", run: do(func(e *Editor) {
+			e.cycleBuffer(-1)
+		})},
+		{name: "pick-buffer", help: "Choose a buffer to show", run: do((*Editor).pickBuffer)},
+		{name: "window", help: "Split, close and move between windows", run: do((*Editor).windowCommand)},
+		{name: "line-numbers", help: "Change how line numbers show", run: do((*Editor).cycleLineNumbers)},
+		{name: "format", help: "Change line endings, BOM and final newline", run: do((*Editor).formatCommand)},
+		{name: "tabs", help: "Change tab width and indenting", run: do((*Editor).tabCommand)},
+		{name: "redraw", help: "Redraw the screen", run: do(func(e *Editor) {})},
+	}
+}
+
+// runCommand runs cmd, noting it as the current command.
+func (e *Editor) runCommand(cmd *command) (bool, error) {
+	e.command = cmd
+	return cmd.run(e)
+}
+
+// commandNamed returns the command called name, or nil.
+func commandNamed(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// lineStart moves the cursor to the start of the line.
+func (e *Editor) lineStart() {
+	e.cx = 0
+}
+
+// lineEnd moves the cursor to the end of the line.
+func (e *Editor) lineEnd() {
+	if e.cy < e.numRows {
+		e.cx = e.rows[e.cy].Size
+	}
+}
@@ -0,0 +1,145 @@
+package editor
+
+import (
+	"fmt"
+)
+
+/*** diff ***/
+
+// Past this many cells, the longest common subsequence table
+// gets too big, and the middle of the files just counts as
+// all different.
+const maxDiffCells = 4000000
+
+// matchLines pairs up the lines of a and b that stay the same
+// going from a to b. Element i of the result is the index in b
+// of line a[i], or -1 if a[i] isn't in b.
+func matchLines(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		m[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		m[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if len(ma)*len(mb) > maxDiffCells {
+		return m
+	}
+	// lcs[i][j] is the length of the longest common
+	// subsequence of ma[i:] and mb[j:].
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			switch {
+			case ma[i] == mb[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(ma) && j < len(mb); {
+		switch {
+		case ma[i] == mb[j]:
+			m[pre+i] = pre + j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return m
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	a, b int  // line numbers in a and b, counting from 0
+	text string
+}
+
+func diffOps(a, b []string) []diffOp {
+	m := matchLines(a, b)
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && m[i] == -1:
+			ops = append(ops, diffOp{'-', i, j, a[i]})
+			i++
+		case i < len(a) && m[i] == j:
+			ops = append(ops, diffOp{' ', i, j, a[i]})
+			i++
+			j++
+		default:
+			ops = append(ops, diffOp{'+', i, j, b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff describes the changes from lines a to lines b
+// in the style of diff -u, with three lines of context.
+func unifiedDiff(aName, bName string, a, b []string) []string {
+	const context = 3
+	ops := diffOps(a, b)
+	out := []string{"--- " + aName, "+++ " + bName}
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// Stretch the hunk until there's a long enough
+		// run of unchanged lines to end it.
+		end, same := start, 0
+		for end < len(ops) && same <= 2*context {
+			if ops[end].kind == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+			end++
+		}
+		end -= same
+		lo := start - context
+		if lo < 0 {
+			lo = 0
+		}
+		hi := end + context
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		na, nb := 0, 0
+		for _, op := range ops[lo:hi] {
+			if op.kind != '+' {
+				na++
+			}
+			if op.kind != '-' {
+				nb++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%d,%d +%d,%d @@", ops[lo].a+1, na, ops[lo].b+1, nb))
+		for _, op := range ops[lo:hi] {
+			out = append(out, string(op.kind)+op.text)
+		}
+		start = end
+	}
+	return out
+}
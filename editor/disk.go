@@ -0,0 +1,172 @@
+package editor
+
+import (
+	"GoKilo/filemgt"
+	"GoKilo/keyboard"
+	"GoKilo/row"
+)
+
+/*** file on disk ***/
+
+// OpenFile adds a buffer for file filename, and reads the file
+// into it. The buffer stays, with no lines, if the file can't be read.
+func (e *Editor) OpenFile(filename string) error {
+	e.NewBuffer(filename)
+	var err error
+	e.Format, err = filemgt.Open(filename, e.AppendRow)
+	e.stamp, _ = filemgt.StampOf(filename)
+	e.Dirty = false
+	e.UpdateAllSyntax()
+	return err
+}
+
+// diskChanged reports whether something other than this editor
+// changed the current buffer's file since it got read or saved.
+func (e *Editor) diskChanged() (filemgt.Stamp, bool) {
+	if e.Filename == "" {
+		return e.stamp, false
+	}
+	now, changed := filemgt.Changed(e.Filename, e.stamp)
+	if !changed {
+		// Maybe just touched, so no need to look at it again.
+		e.stamp = now
+	}
+	return now, changed
+}
+
+// checkDisk runs while the user isn't typing, and asks what to do
+// if the current buffer's file changed on disk. It only asks once
+// for each change.
+func (e *Editor) checkDisk() {
+	now, changed := e.diskChanged()
+	if !changed || now == e.ignored {
+		return
+	}
+	e.ignored = now
+	e.resolveConflict(false)
+	e.RefreshScreen()
+}
+
+// resolveConflict asks what to do about the current buffer's file
+// changing on disk. It returns true if the user wants to go ahead
+// and overwrite the file with the buffer.
+func (e *Editor) resolveConflict(saving bool) bool {
+	for {
+		what := "(i)gnore"
+		if saving {
+			what = "(c)ancel save"
+		}
+		e.SetStatusMessage("%.20s changed on disk! (r)eload | (o)verwrite | (d)iff | %s", e.Filename, what)
+		e.RefreshScreen()
+		c, err := e.readKey()
+		if err != nil {
+			return false
+		}
+		switch c {
+		case 'r', 'R':
+			e.reload()
+			return false
+		case 'o', 'O':
+			if !saving {
+				e.writeFile()
+			}
+			return true
+		case 'd', 'D':
+			e.showDiffDisk()
+			return false
+		case 'i', 'I', 'c', 'C', keyboard.ESCAPE:
+			e.SetStatusMessage("")
+			return false
+		}
+	}
+}
+
+// readDisk reads the current buffer's file, without touching the buffer.
+func (e *Editor) readDisk() ([]string, filemgt.Format, error) {
+	var lines []string
+	format, err := filemgt.Open(e.Filename, func(line []byte) {
+		lines = append(lines, string(line))
+	})
+	return lines, format, err
+}
+
+func (e *Editor) bufferLines() []string {
+	lines := make([]string, e.numRows)
+	for i, r := range e.rows {
+		lines[i] = string(row.Bytes(r.Chars))
+	}
+	return lines
+}
+
+// reload replaces the current buffer's lines with what's in the file
+// now. The cursor stays on the same line of text, if that line is
+// still there, and the reload can be undone.
+func (e *Editor) reload() {
+	lines, format, err := e.readDisk()
+	if err != nil {
+		e.SetStatusMessage("Can't reload: %s", err)
+		return
+	}
+	// The reload is an undo step of its own, even when it comes in
+	// the middle of a command, like a save.
+	u := &e.undoLog
+	outer, depth := u.current, u.depth
+	u.current, u.depth = nil, 0
+	e.beginUndoStep()
+	e.setLines(lines)
+	e.endUndoStep(u)
+	u.current, u.depth = outer, depth
+	e.Format = format
+	e.stamp, _ = filemgt.StampOf(e.Filename)
+	e.ignored = filemgt.Stamp{}
+	e.UpdateAllSyntax()
+	e.markSaved()
+	e.Dirty = false
+	e.writeSwap(true)
+	e.SetStatusMessage("Reloaded %.20s from disk", e.Filename)
+}
+
+// setLines replaces all of the current buffer's lines. The cursor
+// stays on the same line of text, if that line is still there.
+func (e *Editor) setLines(lines []string) {
+	m := matchLines(e.bufferLines(), lines)
+	cy := 0
+	for i := e.cy; i >= 0; i-- {
+		if i < len(m) && m[i] >= 0 {
+			cy = m[i] + e.cy - i
+			break
+		}
+	}
+	cx := e.cx
+
+	for e.numRows > 0 {
+		e.delRow(e.numRows - 1)
+	}
+	for _, line := range lines {
+		e.insertRow(e.numRows, row.Runes([]byte(line)))
+	}
+	e.placeCursor(cx, cy)
+}
+
+// showDiffDisk puts the differences between the current buffer and
+// its file on disk in a new buffer.
+func (e *Editor) showDiffDisk() {
+	lines, _, err := e.readDisk()
+	if err != nil {
+		e.SetStatusMessage("Can't read %.20s: %s", e.Filename, err)
+		return
+	}
+	e.showDiff(e.Filename+" (buffer)", e.Filename+" (disk)", e.bufferLines(), lines)
+	e.SetStatusMessage("Differences from the file on disk. %s to go back.", e.keyFor("prev-buffer"))
+}
+
+// showDiff puts the differences between lines a and lines b in a
+// new buffer.
+func (e *Editor) showDiff(aName, bName string, a, b []string) {
+	diff := unifiedDiff(aName, bName, a, b)
+	e.NewBuffer("")
+	for _, line := range diff {
+		e.AppendRow([]byte(line))
+	}
+	e.Dirty = false
+}
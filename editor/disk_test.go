@@ -0,0 +1,55 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUndoAfterShrinkingReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "five.txt")
+	if err := os.WriteFile(name, []byte("1\n2\n3\n4\n5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := newEditor(24, 80)
+	if err := e.OpenFile(name); err != nil {
+		t.Fatal(err)
+	}
+
+	u := e.beginUndoStep()
+	e.cx, e.cy = 1, 4
+	e.insertChar('x')
+	e.endUndoStep(u)
+
+	if err := os.WriteFile(name, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e.reload()
+	if got, want := e.bufferLines(), []string{"one"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after reload, lines = %q, want %q", got, want)
+	}
+	if e.Dirty {
+		t.Error("dirty right after reload")
+	}
+
+	e.undo()
+	if got, want := e.bufferLines(), []string{"1", "2", "3", "4", "5x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after undoing the reload, lines = %q, want %q", got, want)
+	}
+	if !e.Dirty {
+		t.Error("not dirty after undoing the reload")
+	}
+	e.undo()
+	if got, want := e.bufferLines(), []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after undoing the typing, lines = %q, want %q", got, want)
+	}
+	e.redo()
+	e.redo()
+	if got, want := e.bufferLines(), []string{"one"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after redoing both, lines = %q, want %q", got, want)
+	}
+	if e.Dirty {
+		t.Error("dirty after redoing back to the reloaded file")
+	}
+}
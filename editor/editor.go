@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 	"unicode"
 
+	"GoKilo/clipboard"
+	"GoKilo/config"
 	"GoKilo/filemgt"
 	"GoKilo/highlighter"
 	"GoKilo/keyboard"
@@ -18,26 +21,34 @@ import (
 /*** defines ***/
 
 const kiloVersion = "0.0.2"
-const kiloQuitTimes = 3
 
-// Editor instances keep track of several things right now,
-// from cursor coords inside of file to screen size to
-// file name read from or to save to. Probably needs refactoring.
+// Editor instances keep track of the screen, the status
+// message, the windows on screen and the buffers holding
+// the files under edit. The current window is embedded, so
+// its fields and those of the buffer it shows are the
+// Editor's fields.
 type Editor struct {
-	cx            int
-	cy            int
-	rx            int
-	rowoff        int
-	coloff        int
-	screenRows    int
-	screenCols    int
-	numRows       int
-	rows          []*row.Row
-	Dirty         bool
-	Filename      string
+	*window
+	root          *pane
+	buffers       []*buffer
+	keyList       *buffer // the buffer showKeys lists the keys in
+	termRows      int
+	termCols      int
+	lineNumbers   gutterMode
+	settings      config.Settings
+	keys          *keymap
+	command       *command // the command for the key being handled
+	lastCommand   *command // the command for the key before it
+	vi            *viState // nil unless vi keys are on
+	clipboard     [][]rune
+	killRing      killRing
+	system        clipboard.Clipboard
+	pending       bytes.Buffer // escape sequences to send with the next refresh
 	statusmsg     string
 	statusMsgTime time.Time
-	syntax        *highlighter.Syntax
+	line          *lineEditor         // the line being typed at a prompt, if any
+	histories     map[string][]string // lines typed at each kind of prompt
+	quitTimes     int                 // Ctrl-Qs still needed to quit with unsaved changes
 }
 
 // UpdateAllSyntax redoes all the syntax highlighting, for
@@ -52,8 +63,15 @@ func (e *Editor) UpdateAllSyntax() {
 }
 
 func (e *Editor) updateSyntax(at int) {
+	e.updateSyntaxRange(at, at)
+}
+
+// updateSyntaxRange redoes the highlighting of lines at through
+// last, and of the lines after them for as long as a change to
+// whether a comment is open carries on down the file.
+func (e *Editor) updateSyntaxRange(at, last int) {
 	inComment := at > 0 && e.rows[at-1].HlOpenComment
-	for at < e.numRows && e.syntax.UpdateSyntax(e.rows[at], inComment) {
+	for at < e.numRows && (e.syntax.UpdateSyntax(e.rows[at], inComment) || at < last) {
 		at++
 		inComment = at > 0 && e.rows[at-1].HlOpenComment
 	}
@@ -62,68 +80,104 @@ func (e *Editor) updateSyntax(at int) {
 // AppendRow puts a line of text from the edited file
 // at the end of the internal representation of the file.
 func (e *Editor) AppendRow(s []byte) {
-	e.insertRow(e.numRows, s)
+	e.insertRow(e.numRows, row.Runes(s))
+	e.rows[e.numRows-1].Modified = false
 }
 
-func (e *Editor) insertRow(at int, s []byte) {
+func (e *Editor) insertRow(at int, s []rune) {
 	if at < 0 || at > e.numRows {
 		return
 	}
-	var r row.Row
-	r.Chars = s
-	r.Size = len(s)
-
-	switch at {
-	case 0:
-		t := make([]*row.Row, 1)
-		t[0] = &r
-		e.rows = append(t, e.rows...)
-	case e.numRows:
-		e.rows = append(e.rows, &r)
-	default:
-		t := make([]*row.Row, 1)
-		t[0] = &r
-		e.rows = append(e.rows[:at], append(t, e.rows[at:]...)...)
-	}
-
-	e.rows[at].UpdateRow()
+	e.insertRows(at, [][]rune{s})
 	e.updateSyntax(at)
-	e.numRows++
+}
+
+// insertRows puts in a new row at index at for each of lines, all
+// at once. The caller has to update the new rows' highlighting.
+func (e *Editor) insertRows(at int, lines [][]rune) {
+	rows := make([]*row.Row, len(lines))
+	for i, s := range lines {
+		rows[i] = &row.Row{Chars: s, Size: len(s), Modified: true, TabStop: e.tabStop}
+		rows[i].UpdateRow()
+		e.recordEdit(editInsertRow, at+i, nil, s)
+	}
+	e.rows = append(e.rows[:at], append(rows, e.rows[at:]...)...)
+	e.numRows += len(lines)
+	e.changes += len(lines)
 	e.Dirty = true
 }
 
 func (e *Editor) delRow(at int) {
-	if at < 0 || at > e.numRows {
+	if at < 0 || at >= e.numRows {
 		return
 	}
+	e.recordEdit(editDeleteRow, at, e.rows[at].Chars, nil)
 	e.rows = append(e.rows[:at], e.rows[at+1:]...)
 	e.numRows--
+	e.changes++
+	if at < e.numRows {
+		e.updateSyntax(at)
+	}
+	e.Dirty = true
+}
+
+// changeRow runs f on the row at index at, recording
+// the row's contents before and after for undo.
+func (e *Editor) changeRow(at int, f func(*row.Row)) {
+	e.editRow(at, f)
+	e.updateSyntax(at)
+}
+
+// editRow does the work of changeRow, except for updating
+// the row's highlighting, which is up to the caller.
+func (e *Editor) editRow(at int, f func(*row.Row)) {
+	before := copyRunes(e.rows[at].Chars)
+	f(e.rows[at])
+	e.rows[at].Modified = true
+	e.changes++
+	e.recordEdit(editChangeRow, at, before, e.rows[at].Chars)
 	e.Dirty = true
 }
 
-func (e *Editor) insertChar(c byte) {
+func (e *Editor) insertChar(c rune) {
 	if e.cy == e.numRows {
-		var emptyRow []byte
-		e.AppendRow(emptyRow)
+		e.insertRow(e.numRows, nil)
 	}
-	e.rows[e.cy].RowInsertChar(e.cx, c)
-	e.updateSyntax(e.cy)
-	e.Dirty = true
+	e.changeRow(e.cy, func(r *row.Row) { r.RowInsertChar(e.cx, c) })
 	e.cx++
 }
 
+// insertNewLine splits the line at the cursor. The new line gets
+// the same indentation as the old one, or one more level if the
+// old one ends in something the filetype indents after.
 func (e *Editor) insertNewLine() {
 	if e.cx == 0 {
-		e.insertRow(e.cy, make([]byte, 0))
-	} else {
-		e.insertRow(e.cy+1, e.rows[e.cy].Chars[e.cx:])
-		e.rows[e.cy].Chars = e.rows[e.cy].Chars[:e.cx]
-		e.rows[e.cy].Size = len(e.rows[e.cy].Chars)
-		e.rows[e.cy].UpdateRow()
-		e.updateSyntax(e.cy)
+		e.insertRow(e.cy, make([]rune, 0))
+		e.cy++
+		return
 	}
+	chars := e.rows[e.cy].Chars
+	base := copyRunes(leadingSpace(chars[:e.cx]))
+	indent := base
+	if e.syntax.IndentsAfter(chars[:e.cx]) {
+		indent = append(copyRunes(base), e.indentUnit()...)
+	}
+	tail := chars[e.cx:]
+	tail = tail[len(leadingSpace(tail)):]
+	if len(indent) > len(base) && len(tail) > 0 && e.syntax.DedentsOn(tail[0]) {
+		// Splitting a pair of brackets: the closing one
+		// goes on a line of its own, under the opening one.
+		e.insertRow(e.cy+1, append(base, tail...))
+		tail = nil
+	}
+	e.insertRow(e.cy+1, append(copyRunes(indent), tail...))
+	e.changeRow(e.cy, func(r *row.Row) {
+		r.Chars = r.Chars[:e.cx]
+		r.Size = len(r.Chars)
+		r.UpdateRow()
+	})
 	e.cy++
-	e.cx = 0
+	e.cx = len(indent)
 }
 
 func (e *Editor) delChar() {
@@ -134,26 +188,27 @@ func (e *Editor) delChar() {
 		return
 	}
 	if e.cx > 0 {
-		e.rows[e.cy].RowDelChar(e.cx - 1)
-		e.updateSyntax(e.cy)
-		e.cx--
+		e.changeRow(e.cy, func(r *row.Row) {
+			for prev := r.PrevGrapheme(e.cx); e.cx > prev; e.cx-- {
+				r.RowDelChar(e.cx - 1)
+			}
+		})
 	} else {
 		e.cx = e.rows[e.cy-1].Size
-		e.rows[e.cy-1].RowAppendString(e.rows[e.cy].Chars)
-		e.updateSyntax(e.cy - 1)
-		e.Dirty = true
+		e.changeRow(e.cy-1, func(r *row.Row) { r.RowAppendString(e.rows[e.cy].Chars) })
 		e.delRow(e.cy)
 		e.cy--
 	}
 	e.Dirty = true
 }
 
-func (e *Editor) rowsToString() (buf string, totlen int) {
-	for _, aRow := range e.rows {
-		totlen += aRow.Size + 1
-		buf += string(aRow.Chars) + "\n"
+func (b *buffer) rowsToString() (buf string, totlen int) {
+	lines := make([][]byte, b.numRows)
+	for i, aRow := range b.rows {
+		lines[i] = row.Bytes(aRow.Chars)
 	}
-	return
+	buf = string(b.Format.Encode(lines))
+	return buf, len(buf)
 }
 
 /*** find ***/
@@ -163,24 +218,49 @@ var lastMatch = -1
 var direction = 1
 var savedHlLine int
 var savedHl []byte
+var searchAs = searchLiteral
+var searchErr error
+
+// highlightMatch colors length runes at position cx of line at
+// as a search match, saving the line's highlighting so that
+// restoreHighlight can put it back.
+func (e *Editor) highlightMatch(at, cx, length int) {
+	thisRow := e.rows[at]
+	savedHlLine = at
+	savedHl = make([]byte, thisRow.Rsize)
+	copy(savedHl, thisRow.Hl)
+	max := thisRow.RenderIndex(cx + length)
+	for i := thisRow.RenderIndex(cx); i < max; i++ {
+		thisRow.Hl[i] = highlighter.HL_MATCH
+	}
+}
 
-func (e *Editor) findCallback(qry []byte, key int) {
-
-	if savedHlLine > 0 {
+func (e *Editor) restoreHighlight() {
+	if savedHl != nil && savedHlLine < e.numRows {
 		copy(e.rows[savedHlLine].Hl, savedHl)
-		savedHlLine = 0
-		savedHl = nil
 	}
+	savedHlLine = 0
+	savedHl = nil
+}
+
+func (e *Editor) findCallback(qry []rune, key int) {
+
+	e.restoreHighlight()
 
 	switch key {
 	case '\r', keyboard.ESCAPE:
 		lastMatch = -1
 		direction = 1
+		hitMatcher = nil
 		return
-	case keyboard.ARROW_RIGHT, keyboard.ARROW_DOWN:
+	case keyboard.ARROW_DOWN:
 		direction = 1
-	case keyboard.ARROW_LEFT, keyboard.ARROW_UP:
+	case keyboard.ARROW_UP:
 		direction = -1
+	case keyboard.CTRL_T:
+		searchAs = (searchAs + 1) % (searchRegexp + 1)
+		lastMatch = -1
+		direction = 1
 	default:
 		lastMatch = -1
 		direction = 1
@@ -191,6 +271,13 @@ func (e *Editor) findCallback(qry []byte, key int) {
 	}
 	current := lastMatch
 
+	var match matcher
+	hitMatcher = nil
+	if match, searchErr = newMatcher(qry, searchAs); searchErr != nil || len(qry) == 0 {
+		return
+	}
+	hitMatcher = match
+
 	for range e.rows {
 		current += direction
 		if current == -1 {
@@ -198,32 +285,38 @@ func (e *Editor) findCallback(qry []byte, key int) {
 		} else if current == e.numRows {
 			current = 0
 		}
-		thisRow := e.rows[current]
-		x := bytes.Index(thisRow.Render, qry)
-		if x > -1 {
+		if x, end := match(e.rows[current].Chars, 0); x > -1 {
 			lastMatch = current
 			e.cy = current
-			e.cx = thisRow.RowRxToCx(x)
+			e.cx = x
 			e.rowoff = e.numRows
-			savedHlLine = current
-			savedHl = make([]byte, thisRow.Rsize)
-			copy(savedHl, thisRow.Hl)
-			max := x + len(qry)
-			for i := x; i < max; i++ {
-				thisRow.Hl[i] = highlighter.HL_MATCH
-			}
+			e.highlightMatch(current, x, end-x)
 			break
 		}
 	}
 }
 
+// findPrompt shows the search mode, and any problem
+// with a regular expression, along with the query.
+func findPrompt(buf string) string {
+	msg := fmt.Sprintf("Search (%s): %s (Ctrl-T/ESC/Up/Down/Enter)", searchAs, buf)
+	if searchErr != nil {
+		msg += " - " + searchErr.Error()
+	}
+	return msg
+}
+
 func find(e *Editor) {
 	savedCx := e.cx
 	savedCy := e.cy
 	savedColoff := e.coloff
 	savedRowoff := e.rowoff
-	query, _ := e.prompt("Search: %s (ESC/Arrows/Enter)", e.findCallback)
+	searchErr = nil
+	query, _, _ := e.readLine(findPrompt, lineOptions{history: "search", arrows: true, callback: e.findCallback})
 	// XXX - what to do with the error return here?
+	if searchErr != nil && query != "" {
+		e.SetStatusMessage("Invalid pattern: %s", searchErr)
+	}
 	if query == "" {
 		e.cx = savedCx
 		e.cy = savedCy
@@ -234,45 +327,18 @@ func find(e *Editor) {
 
 /*** input ***/
 
-func (e *Editor) prompt(prompt string, callback func([]byte, int)) (string, error) {
-	var buf []byte
-
-	for {
-		e.SetStatusMessage(prompt, buf)
-		e.RefreshScreen()
-
-		c, err := keyboard.ReadKey()
-		if err != nil {
-			return "", err
-		}
+// prompt reads a line at a prompt made from a format string with
+// a single verb for what the user typed.
+func (e *Editor) prompt(prompt string, opt lineOptions) (string, error) {
+	s, _, err := e.readLine(promptFormat(prompt), opt)
+	return s, err
+}
 
-		switch c {
-		case keyboard.DEL_KEY, keyboard.CTRL_H, keyboard.BACKSPACE:
-			if len(buf) > 0 {
-				buf = buf[:len(buf)-1]
-			}
-		case keyboard.ESCAPE:
-			e.SetStatusMessage("")
-			if callback != nil {
-				callback(buf, c)
-			}
-			return "", nil
-		case '\r':
-			if len(buf) != 0 {
-				e.SetStatusMessage("")
-				if callback != nil {
-					callback(buf, c)
-				}
-				return string(buf), nil
-			}
-		default:
-			if unicode.IsPrint(rune(c)) {
-				buf = append(buf, byte(c))
-			}
-		}
-		if callback != nil {
-			callback(buf, c)
-		}
+// promptFormat makes a func for readLine out of a format
+// string with a single verb for what the user typed.
+func promptFormat(format string) func(string) string {
+	return func(buf string) string {
+		return fmt.Sprintf(format, buf)
 	}
 }
 
@@ -280,7 +346,7 @@ func (e *Editor) moveCursor(key int) {
 	switch key {
 	case keyboard.ARROW_LEFT:
 		if e.cx != 0 {
-			e.cx--
+			e.cx = e.rows[e.cy].PrevGrapheme(e.cx)
 		} else if e.cy > 0 {
 			e.cy--
 			e.cx = e.rows[e.cy].Size
@@ -288,7 +354,7 @@ func (e *Editor) moveCursor(key int) {
 	case keyboard.ARROW_RIGHT:
 		if e.cy < e.numRows {
 			if e.cx < e.rows[e.cy].Size {
-				e.cx++
+				e.cx = e.rows[e.cy].NextGrapheme(e.cx)
 			} else if e.cx == e.rows[e.cy].Size {
 				e.cy++
 				e.cx = 0
@@ -297,10 +363,12 @@ func (e *Editor) moveCursor(key int) {
 	case keyboard.ARROW_UP:
 		if e.cy != 0 {
 			e.cy--
+			e.cx = e.cxForRx(e.cy, e.rx)
 		}
 	case keyboard.ARROW_DOWN:
 		if e.cy < e.numRows {
 			e.cy++
+			e.cx = e.cxForRx(e.cy, e.rx)
 		}
 	}
 
@@ -313,59 +381,108 @@ func (e *Editor) moveCursor(key int) {
 	}
 }
 
-var quitTimes = kiloQuitTimes
+// cxForRx finds the position in line cy that displays
+// at screen column rx, so vertical motion doesn't land
+// the cursor in the middle of a wide character or a tab.
+func (e *Editor) cxForRx(cy, rx int) int {
+	if cy >= e.numRows {
+		return 0
+	}
+	return e.rows[cy].RowRxToCx(rx)
+}
 
 // ProcessKeypress gets a (possibly multi-byte) keypress from keyboard, then
 // decides what to do to Editor's internal state based on that byte or bytes.
 func (e *Editor) ProcessKeypress() (bool, error) {
-	c, err := keyboard.ReadKey()
+	var cmd *command
+	var c int
+	var err error
+	if e.viCommandMode() {
+		c, err = e.waitKey(e.idle)
+	} else {
+		cmd, c, err = e.readCommand()
+	}
 	if err != nil {
 		return false, err
 	}
-	switch c {
-	case '\r':
-		e.insertNewLine()
-	case keyboard.CTRL_Q:
-		return e.processQuit()
-	case keyboard.CTRL_S:
-		return e.saveFile()
-	case keyboard.HOME_KEY:
-		e.cx = 0
-	case keyboard.END_KEY:
-		if e.cy < e.numRows {
-			e.cx = e.rows[e.cy].Size
-		}
-	case keyboard.CTRL_F:
-		find(e)
-	case keyboard.CTRL_H, keyboard.BACKSPACE, keyboard.DEL_KEY:
-		e.deleteSomething(c)
-	case keyboard.PAGE_UP, keyboard.PAGE_DOWN:
-		e.moveScreenful(c)
-	case keyboard.ARROW_UP, keyboard.ARROW_DOWN,
-		keyboard.ARROW_LEFT, keyboard.ARROW_RIGHT:
-		e.moveCursor(c)
-	case keyboard.CTRL_L:
-	case keyboard.ESCAPE:
+	defer e.endUndoStep(e.beginUndoStep())
+	e.lastCommand, e.command = e.command, nil
+	e.keepMark(cmd)
+	changes := e.changes
+	if again, err := e.runKey(cmd, c); !again || err != nil {
+		return again, err
+	}
+	if e.command == nil || e.command.name != "quit" {
+		e.quitTimes = e.settings.QuitTimes
+	}
+	if e.changes != changes {
+		// Edits move text out from under the mark.
+		e.mark = mark{}
+	}
+	if e.vi != nil && e.vi.mode == viVisual && !e.mark.set {
+		e.vi.mode = viNormal
+	}
+	if e.changes-e.swapped >= swapEvery {
+		e.writeSwap(false)
+	}
+	return true, nil
+}
+
+// runKey does what key c does, running cmd, the command
+// it's bound to, if it isn't nil.
+func (e *Editor) runKey(cmd *command, c int) (bool, error) {
+	if e.viCommandMode() {
+		return e.viKey(c)
+	}
+	if e.vi != nil {
+		e.vi.record(c)
+	}
+	switch {
+	case e.vi != nil && c == keyboard.ESCAPE:
+		e.viLeaveInsert()
+	case e.vi != nil && cmd == nil && keyboard.Decode(c).Mod == keyboard.Alt:
+		// ESC and a key typed fast come in as Alt and the key.
+		e.viLeaveInsert()
+		return e.viKey(keyboard.Decode(c).Key)
+	case cmd != nil:
+		return e.runCommand(cmd)
+	case c == keyboard.PASTE:
+		e.pasteText(keyboard.PastedText())
+	case c > unicode.MaxRune, c == keyboard.ESCAPE:
+		// A special key, or one with modifiers, that
+		// doesn't do anything.
 	default:
-		e.insertChar(byte(c))
+		e.typeChar(rune(c))
+		e.undoLog.current.typing = true
 	}
-	quitTimes = kiloQuitTimes
 	return true, nil
 }
 
 func (e *Editor) deleteSomething(c int) {
 	if c == keyboard.DEL_KEY {
 		e.moveCursor(keyboard.ARROW_RIGHT)
+	} else if e.deleteIndent() {
+		return
 	}
 	e.delChar()
 }
 
 func (e *Editor) processQuit() (bool, error) {
-	if e.Dirty && quitTimes > 0 {
-		e.SetStatusMessage("Warning!!! File has unsaved changes. Press Ctrl-Q %d more times to quit.", quitTimes)
-		quitTimes--
+	if n := e.dirtyBuffers(); n > 0 && e.quitTimes > 0 {
+		what := "File has"
+		if e.Dirty && n > 1 {
+			what = fmt.Sprintf("This and %d other files have", n-1)
+		} else if !e.Dirty {
+			what = fmt.Sprintf("%d other files have", n)
+			if n == 1 {
+				what = "Another file has"
+			}
+		}
+		e.SetStatusMessage("Warning!!! %s unsaved changes. Press %s %d more times to quit.", what, e.keyFor("quit"), e.quitTimes)
+		e.quitTimes--
 		return true, nil
 	}
+	e.removeSwaps()
 	return false, nil
 }
 
@@ -388,7 +505,7 @@ func (e *Editor) moveScreenful(c int) {
 func (e *Editor) saveFile() (bool, error) {
 	if e.Filename == "" {
 		var err error
-		e.Filename, err = e.prompt("Save as: %q", nil)
+		e.Filename, err = e.prompt("Save as: %s (ESC to cancel)", lineOptions{history: "file", complete: completeFile})
 		if e.Filename == "" {
 			e.SetStatusMessage("Save aborted")
 			return true, err
@@ -397,16 +514,40 @@ func (e *Editor) saveFile() (bool, error) {
 			e.SetStatusMessage("%s", err)
 			e.Filename = ""
 		}
-		e.syntax = highlighter.SelectSyntaxHighlight(e.Filename)
+		e.setFilename(e.Filename)
+	}
+	if _, changed := e.diskChanged(); changed && !e.resolveConflict(true) {
+		return true, nil
 	}
+	e.writeFile()
+	return true, nil
+}
+
+// setFilename gives the current buffer a new file name, and the
+// highlighting and tabs that go with it.
+func (e *Editor) setFilename(name string) {
+	e.Filename = name
+	e.syntax = highlighter.SelectSyntaxHighlight(name)
+	e.filetypeTabs(e.buffer)
+	e.setTabStop(e.tabStop)
+}
+
+// writeFile saves the current buffer to its file, without
+// checking if something else changed the file.
+func (e *Editor) writeFile() {
 	var msg string
 	msg, e.Dirty = filemgt.Save(e.Filename, e.rowsToString)
 	e.SetStatusMessage(msg)
+	if !e.Dirty {
+		e.markSaved()
+		e.stamp, _ = filemgt.StampOf(e.Filename)
+		e.ignored = filemgt.Stamp{}
+		e.writeSwap(true)
+	}
 	e.UpdateAllSyntax()
 	if e.Dirty {
 		e.Filename = ""
 	} // Still dirty? File didn't get written.
-	return true, nil
 }
 
 /*** output ***/
@@ -427,105 +568,158 @@ func (e *Editor) scroll() {
 	if e.rx < e.coloff {
 		e.coloff = e.rx
 	}
-	if e.rx >= e.coloff+e.screenCols {
-		e.coloff = e.rx - e.screenCols + 1
+	if e.rx >= e.coloff+e.textCols() {
+		e.coloff = e.rx - e.textCols() + 1
 	}
 }
 
 // RefreshScreen resets the entire screen based on the internal
 // state of an Editor object, and its internal file representation.
+// Each window gets drawn in turn by making it the current window
+// while it's drawn.
 func (e *Editor) RefreshScreen() {
-	e.scroll()
-	ab := bytes.NewBufferString("\x1b[25l")
-	ab.WriteString("\x1b[H")
-	e.drawRows(ab)
-	e.drawStatusBar(ab)
+	ab := bytes.NewBufferString("\x1b[?25l")
+	current := e.window
+	for _, w := range e.root.windows() {
+		e.window = w
+		e.placeCursor(e.cx, e.cy)
+		e.scroll()
+		e.drawRows(ab)
+		e.drawStatusBar(ab, w == current)
+	}
+	e.window = current
+	e.drawSeparators(ab, e.root)
 	e.drawMessageBar(ab)
-	ab.WriteString(fmt.Sprintf("\x1b[%d;%dH", (e.cy-e.rowoff)+1, (e.rx-e.coloff)+1))
+	if e.line != nil {
+		ab.WriteString(fmt.Sprintf("\x1b[%d;%dH", e.termRows, e.line.col+1))
+	} else {
+		ab.WriteString(fmt.Sprintf("\x1b[%d;%dH", e.top+(e.cy-e.rowoff)+1, e.left+e.gutterWidth()+(e.rx-e.coloff)+1))
+	}
 	ab.WriteString("\x1b[?25h")
+	e.pending.WriteTo(ab)
 	_, err := ab.WriteTo(os.Stdout)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func (e *Editor) padRow(ab *bytes.Buffer) {
+func (e *Editor) padRow(ab *bytes.Buffer) int {
 	w := fmt.Sprintf("Kilo editor -- version %s", kiloVersion)
-	if len(w) > e.screenCols {
-		w = w[0:e.screenCols]
+	if len(w) > e.textCols() {
+		w = w[0:e.textCols()]
 	}
 	pad := "~ "
-	for padding := (e.screenCols - len(w)) / 2; padding > 0; padding-- {
+	padding := (e.textCols() - len(w)) / 2
+	for i := padding; i > 0; i-- {
 		ab.WriteString(pad)
 		pad = " "
 	}
 	ab.WriteString(w)
+	if padding < 0 {
+		padding = 0
+	}
+	return padding + len(w)
 }
 
-func (e *Editor) ordinaryRow(filerow int, ab *bytes.Buffer) {
-	length := e.rows[filerow].Rsize - e.coloff
-	if length < 0 {
-		length = 0
-	}
-	if length > 0 {
-		if length > e.screenCols {
-			length = e.screenCols
+// ordinaryRow draws the visible part of line filerow, returning
+// the number of screen columns it took up.
+func (e *Editor) ordinaryRow(filerow int, ab *bytes.Buffer) int {
+	rw := e.rows[filerow]
+	currentColor := -1
+	col := 0
+	visible := false
+	selFrom, selTo := e.selectedRender(filerow)
+	inverse := false
+	for j, c := range rw.Render {
+		w := rw.RenderWidth(j)
+		if w == 0 && !visible {
+			// Combining mark on a character that scrolled off.
+			continue
+		}
+		if col < e.coloff {
+			col += w
+			if col > e.coloff {
+				// A wide character cut in half by the left
+				// edge of the screen shows as a blank.
+				ab.WriteString(strings.Repeat(" ", col-e.coloff))
+			}
+			continue
 		}
-		rindex := e.coloff + length
-		rw := e.rows[filerow]
-		hl := rw.Hl[e.coloff:rindex]
-		currentColor := -1
-		for j, c := range e.rows[filerow].Render[e.coloff:rindex] {
-			switch {
-			case unicode.IsControl(rune(c)):
+		if col+w > e.coloff+e.textCols() {
+			break
+		}
+		col += w
+		visible = true
+		if selected := j >= selFrom && j < selTo; selected != inverse {
+			inverse = selected
+			if inverse {
+				ab.WriteString("\x1b[7m")
+			} else {
+				ab.WriteString("\x1b[27m")
+			}
+		}
+		switch {
+		case unicode.IsControl(c) || unicode.Is(unicode.Cs, c):
+			ab.WriteString("\x1b[7m")
+			if c < 26 {
+				ab.WriteString("@")
+			} else {
+				ab.WriteString("?")
+			}
+			ab.WriteString("\x1b[m")
+			if inverse {
 				ab.WriteString("\x1b[7m")
-				if c < 26 {
-					ab.WriteString("@")
-				} else {
-					ab.WriteString("?")
-				}
-				ab.WriteString("\x1b[m")
-				if currentColor != -1 {
-					ab.WriteString(fmt.Sprintf("\x1b[%dm", currentColor))
-				}
-			case hl[j] == highlighter.HL_NORMAL:
-				if currentColor != -1 {
-					ab.WriteString("\x1b[39m")
-					currentColor = -1
-				}
-				ab.WriteByte(c)
-			default:
-				color := highlighter.SyntaxToColor(hl[j])
-				if color != currentColor {
-					currentColor = color
-					buf := fmt.Sprintf("\x1b[%dm", color)
-					ab.WriteString(buf)
-				}
-				ab.WriteByte(c)
 			}
+			if currentColor != -1 {
+				ab.WriteString(fmt.Sprintf("\x1b[%dm", currentColor))
+			}
+		default:
+			color := e.settings.Colors.SyntaxToColor(rw.Hl[j])
+			if color != currentColor {
+				currentColor = color
+				buf := fmt.Sprintf("\x1b[%dm", color)
+				ab.WriteString(buf)
+			}
+			ab.WriteRune(c)
 		}
-		ab.WriteString("\x1b[39m")
 	}
+	ab.WriteString("\x1b[27;39m")
+	if col < e.coloff {
+		return 0
+	}
+	return col - e.coloff
 }
 
+// drawRows draws the current window's lines, blanking out
+// whatever is left of each screen row inside the window.
 func (e *Editor) drawRows(ab *bytes.Buffer) {
 	for y := 0; y < e.screenRows; y++ {
+		ab.WriteString(fmt.Sprintf("\x1b[%d;%dH", e.top+y+1, e.left+1))
 		filerow := y + e.rowoff
+		e.drawGutter(ab, filerow)
+		width := 1
 		if filerow >= e.numRows {
-			if e.numRows == 0 && y == e.screenRows/3 {
-				e.padRow(ab)
+			if e.numRows == 0 && y == e.screenRows/3 && len(e.root.windows()) == 1 {
+				width = e.padRow(ab)
 			} else {
 				ab.WriteString("~")
 			}
 		} else {
-			e.ordinaryRow(filerow, ab)
+			width = e.ordinaryRow(filerow, ab)
+		}
+		if width < e.textCols() {
+			ab.WriteString(strings.Repeat(" ", e.textCols()-width))
 		}
-		ab.WriteString("\x1b[K")
-		ab.WriteString("\r\n")
 	}
 }
 
-func (e *Editor) drawStatusBar(ab *bytes.Buffer) {
+// drawStatusBar draws the status line under the current window,
+// in bold if it's the window the user is working in.
+func (e *Editor) drawStatusBar(ab *bytes.Buffer, active bool) {
+	ab.WriteString(fmt.Sprintf("\x1b[%d;%dH", e.top+e.screenRows+1, e.left+1))
+	if active {
+		ab.WriteString("\x1b[1m")
+	}
 	ab.WriteString("\x1b[7m")
 	fname := e.Filename
 	if fname == "" {
@@ -536,6 +730,12 @@ func (e *Editor) drawStatusBar(ab *bytes.Buffer) {
 		modified = "(modified)"
 	}
 	status := fmt.Sprintf("%.20s - %d lines %s", fname, e.numRows, modified)
+	if len(e.buffers) > 1 {
+		status = fmt.Sprintf("[%d/%d] %s", e.bufferIndex()+1, len(e.buffers), status)
+	}
+	if active && e.vi != nil {
+		status = fmt.Sprintf("%s  %s", viModeNames[e.vi.mode], status)
+	}
 	ln := len(status)
 	if ln > e.screenCols {
 		ln = e.screenCols
@@ -544,7 +744,7 @@ func (e *Editor) drawStatusBar(ab *bytes.Buffer) {
 	if e.syntax != nil {
 		filetype = e.syntax.Filetype
 	}
-	rstatus := fmt.Sprintf("%s | %d/%d", filetype, e.cy+1, e.numRows)
+	rstatus := fmt.Sprintf("%s | %s | %d/%d", filetype, e.Format, e.cy+1, e.numRows)
 	rlen := len(rstatus)
 	ab.WriteString(status[:ln])
 	for ln < e.screenCols {
@@ -557,23 +757,77 @@ func (e *Editor) drawStatusBar(ab *bytes.Buffer) {
 		}
 	}
 	ab.WriteString("\x1b[m")
-	ab.WriteString("\r\n")
 }
 
 func (e *Editor) drawMessageBar(ab *bytes.Buffer) {
+	ab.WriteString(fmt.Sprintf("\x1b[%d;1H", e.termRows))
 	ab.WriteString("\x1b[K")
-	msglen := len(e.statusmsg)
-	if msglen > e.screenCols {
-		msglen = e.screenCols
+	msg := fitWidth(e.statusmsg, e.termCols)
+	timeout := e.settings.StatusTimeout
+	if msg != "" && (timeout == 0 || time.Now().Sub(e.statusMsgTime) < timeout) {
+		ab.WriteString(msg)
 	}
-	if msglen > 0 && (time.Now().Sub(e.statusMsgTime) < 5*time.Second) {
-		ab.WriteString(e.statusmsg)
+}
+
+// fitWidth cuts s down to the characters that fit in cols
+// columns on screen, never splitting a character.
+func fitWidth(s string, cols int) string {
+	width := 0
+	for i, r := range s {
+		width += row.RuneWidth(r)
+		if width > cols {
+			return s[:i]
+		}
 	}
+	return s
+}
+
+// readKey gets a keypress from keyboard, dealing with any
+// changes in window size while waiting for it.
+func (e *Editor) readKey() (int, error) {
+	return e.waitKey(nil)
+}
+
+// waitKey does the work of readKey, running onIdle, if it
+// isn't nil, whenever the user stops typing for a while.
+func (e *Editor) waitKey(onIdle func()) (int, error) {
+	for {
+		c, err := keyboard.ReadKey()
+		switch c {
+		case keyboard.RESIZE:
+			e.resize()
+			e.RefreshScreen()
+		case keyboard.IDLE:
+			if onIdle != nil {
+				onIdle()
+			}
+		default:
+			return c, err
+		}
+	}
+}
+
+// resize gets the new terminal size after the user
+// changes it, and keeps the scroll offsets sensible.
+func (e *Editor) resize() {
+	rows, cols, ok := screen.GetWindowSize()
+	if !ok {
+		return
+	}
+	e.termRows = clamp(rows, 3, rows)
+	e.termCols = clamp(cols, 1, cols)
+	e.layout()
+	for _, w := range e.root.windows() {
+		if w.rowoff > w.numRows {
+			w.rowoff = w.numRows
+		}
+	}
+	e.scroll()
 }
 
 // SetStatusMessage invocations should set the text of
-// the message the user sees for 5 seconds at the bottom
-// of the screen.
+// the message the user sees for a few seconds (see
+// config.Settings.StatusTimeout) at the bottom of the screen.
 func (e *Editor) SetStatusMessage(args ...interface{}) {
 	e.statusmsg = fmt.Sprintf(args[0].(string), args[1:]...)
 	e.statusMsgTime = time.Now()
@@ -583,11 +837,22 @@ func (e *Editor) SetStatusMessage(args ...interface{}) {
 
 // NewEditor creates an instance of Editor, fresh and ready to go.
 func NewEditor() (*Editor, error) {
-	var ec Editor
-	var e bool
-	if ec.screenRows, ec.screenCols, e = screen.GetWindowSize(); !e {
+	rows, cols, ok := screen.GetWindowSize()
+	if !ok {
 		return nil, fmt.Errorf("couldn't get screen size")
 	}
-	ec.screenRows -= 2
-	return &ec, nil
+	ec := newEditor(rows, cols)
+	ec.system = clipboard.Default(&clipboard.Terminal{Out: &ec.pending, Ask: ec.askTerminal})
+	return ec, nil
+}
+
+// newEditor does the work of NewEditor, for a screen of the
+// given size.
+func newEditor(rows, cols int) *Editor {
+	ec := &Editor{termRows: rows, termCols: cols}
+	ec.Configure(config.Default())
+	ec.window = &window{}
+	ec.root = &pane{win: ec.window}
+	ec.layout()
+	return ec
 }
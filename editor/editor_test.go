@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"GoKilo/config"
+)
+
+func TestFitWidth(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		cols int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "hé"},
+		{"日本語", 4, "日本"},
+		{"日本語", 5, "日本"},
+		{"a日", 2, "a"},
+		{"", 3, ""},
+		{"abc", 0, ""},
+	} {
+		if got := fitWidth(tt.s, tt.cols); got != tt.want {
+			t.Errorf("fitWidth(%q, %d) = %q, want %q", tt.s, tt.cols, got, tt.want)
+		}
+	}
+}
+
+func TestNormalColor(t *testing.T) {
+	e := newEditor(24, 80)
+	s := config.Default()
+	if err := s.Set("colors.normal", "yellow"); err != nil {
+		t.Fatal(err)
+	}
+	e.Configure(s)
+	e.NewBuffer("")
+	e.AppendRow([]byte("plain"))
+	var ab bytes.Buffer
+	e.ordinaryRow(0, &ab)
+	if !strings.Contains(ab.String(), "\x1b[33mplain") {
+		t.Errorf("row drew as %q, want plain text in yellow", ab.String())
+	}
+}
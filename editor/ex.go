@@ -0,0 +1,370 @@
+package editor
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"GoKilo/config"
+	"GoKilo/filemgt"
+	"GoKilo/row"
+)
+
+/*** ex commands ***/
+
+// An exCommand is a command typed by name at the : prompt, with
+// an argument after it, and maybe a ! on the end of the name.
+type exCommand struct {
+	names []string // the name, then any short ones
+	arg   string   // what the argument is: "file", "setting", or ""
+	run   func(e *Editor, arg string, force bool) (bool, error)
+}
+
+// exCommands are the commands the : prompt knows, besides numbers,
+// substitutions, and the names of the commands keys are bound to.
+var exCommands = []*exCommand{
+	{names: []string{"write", "w"}, arg: "file", run: (*Editor).exWrite},
+	{names: []string{"quit", "q"}, run: (*Editor).exQuit},
+	{names: []string{"wq", "xit", "x"}, arg: "file", run: func(e *Editor, arg string, force bool) (bool, error) {
+		if again, err := e.exWrite(arg, force); !again || err != nil || e.Dirty {
+			return again, err
+		}
+		return e.exQuit("", force)
+	}},
+	{names: []string{"edit", "e"}, arg: "file", run: (*Editor).exEdit},
+	{names: []string{"goto", "go"}, run: func(e *Editor, arg string, force bool) (bool, error) {
+		line, col, err := parsePosition(arg, e.cy)
+		if err != nil {
+			e.SetStatusMessage("%s", err)
+			return true, nil
+		}
+		e.GotoPosition(line, col)
+		return true, nil
+	}},
+	{names: []string{"set", "se"}, arg: "setting", run: func(e *Editor, arg string, force bool) (bool, error) {
+		e.exSet(arg)
+		return true, nil
+	}},
+}
+
+// exCommandNamed returns the : command called name, or nil.
+func exCommandNamed(name string) *exCommand {
+	for _, c := range exCommands {
+		for _, n := range c.names {
+			if n == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// exPrompt reads a command line after ':' and runs it.
+func (e *Editor) exPrompt() (bool, error) {
+	line, err := e.prompt(":%s", lineOptions{history: "command", complete: completeEx})
+	if err != nil || line == "" {
+		return true, err
+	}
+	return e.runEx(line)
+}
+
+// runEx runs command line. It returns false if it's time to quit.
+func (e *Editor) runEx(line string) (bool, error) {
+	line = strings.TrimSpace(line)
+	if n, err := strconv.Atoi(line); err == nil {
+		e.GotoPosition(n, 0)
+		return true, nil
+	}
+	if isSubstitute(line) {
+		e.substitute(line)
+		return true, nil
+	}
+	name, arg := line, ""
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		name, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	force := strings.HasSuffix(name, "!")
+	name = strings.TrimSuffix(name, "!")
+	if c := exCommandNamed(name); c != nil {
+		return c.run(e, arg, force)
+	}
+	if c := commandNamed(name); c != nil && arg == "" && !force {
+		return e.runCommand(c)
+	}
+	e.SetStatusMessage("Not an editor command: %s", line)
+	return true, nil
+}
+
+// isSubstitute reports whether line is an :s or :%s command,
+// and not a command that starts with s, like :set.
+func isSubstitute(line string) bool {
+	line = strings.TrimPrefix(line, "%")
+	return len(line) > 1 && line[0] == 's' && !unicode.IsLetter(rune(line[1])) && line[1] != ' '
+}
+
+// exWrite saves the current buffer. Given the name of some other
+// file, it writes a copy there instead, as vi does, and the buffer
+// keeps its own name; a buffer with no name yet takes that one.
+func (e *Editor) exWrite(arg string, force bool) (bool, error) {
+	if arg == "" || arg == e.Filename {
+		return e.saveFile()
+	}
+	if e.Filename == "" {
+		e.setFilename(arg)
+		return e.saveFile()
+	}
+	if _, err := os.Stat(arg); err == nil && !force {
+		e.SetStatusMessage("%.20s exists (add ! to overwrite)", arg)
+		return true, nil
+	}
+	msg, _ := filemgt.Save(arg, e.rowsToString)
+	e.SetStatusMessage("%.20s: %s", arg, msg)
+	return true, nil
+}
+
+// exQuit quits, if nothing is unsaved or force is true.
+func (e *Editor) exQuit(arg string, force bool) (bool, error) {
+	if !force && e.dirtyBuffers() > 0 {
+		e.SetStatusMessage("Unsaved changes (add ! to quit anyway)")
+		return true, nil
+	}
+	e.removeSwaps()
+	return false, nil
+}
+
+// exEdit shows the buffer for file arg, reading the file into a
+// new buffer if none has it yet.
+func (e *Editor) exEdit(arg string, force bool) (bool, error) {
+	if arg == "" {
+		e.SetStatusMessage("Want a file name")
+		return true, nil
+	}
+	for i, b := range e.buffers {
+		if b.Filename == arg {
+			e.SwitchBuffer(i)
+			return true, nil
+		}
+	}
+	back := e.buffer
+	err := e.OpenFile(arg)
+	switch {
+	case os.IsNotExist(err):
+		e.SetStatusMessage("New file: %s", arg)
+	case err != nil:
+		e.SetStatusMessage("%s", err)
+		e.dropBuffer(back)
+		return true, nil
+	default:
+		e.SetStatusMessage("%s: %d lines", arg, e.numRows)
+	}
+	if !e.checkSwap() {
+		e.dropBuffer(back)
+		e.SetStatusMessage("")
+		return true, nil
+	}
+	e.buffer.writeSwap(true)
+	return true, nil
+}
+
+// dropBuffer gets rid of the newest buffer, just opened in the
+// current window, and goes back to buffer back.
+func (e *Editor) dropBuffer(back *buffer) {
+	e.buffers = e.buffers[:len(e.buffers)-1]
+	e.showBuffer(back)
+}
+
+// exSet changes a setting: "name=value", "name value", or for
+// settings that are true or false, "name" or "noname". Tab settings
+// change for the current buffer too.
+func (e *Editor) exSet(arg string) {
+	name, value := arg, "true"
+	if i := strings.IndexAny(arg, "= "); i >= 0 {
+		name, value = arg[:i], strings.TrimSpace(arg[i+1:])
+	} else if strings.HasPrefix(arg, "no") {
+		name, value = arg[2:], "false"
+	}
+	s := e.settings
+	if err := s.Set(name, value); err != nil {
+		e.SetStatusMessage("%s", err)
+		return
+	}
+	if err := e.Configure(s); err != nil {
+		e.SetStatusMessage("%s", err)
+		return
+	}
+	switch name {
+	case "tabstop":
+		e.setTabStop(s.TabStop)
+	case "expandtab":
+		e.expandTab = s.ExpandTab
+	}
+	e.SetStatusMessage("%s=%s", name, value)
+}
+
+// completeEx completes a command line: the command's name, or its
+// argument.
+func completeEx(before string) (int, []string) {
+	i := strings.IndexByte(before, ' ')
+	if i < 0 {
+		var names []string
+		add := func(name string) {
+			if strings.HasPrefix(name, before) && !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+		for _, c := range exCommands {
+			add(c.names[0])
+		}
+		for _, c := range commands {
+			add(c.name)
+		}
+		sort.Strings(names)
+		return 0, names
+	}
+	c := exCommandNamed(strings.TrimSuffix(before[:i], "!"))
+	if c == nil {
+		return 0, nil
+	}
+	start := i + 1
+	for start < len(before) && before[start] == ' ' {
+		start++
+	}
+	arg := before[start:]
+	switch c.arg {
+	case "file":
+		return start, fileNames(arg)
+	case "setting":
+		var names []string
+		for _, name := range config.Names {
+			if strings.HasPrefix(name, arg) {
+				names = append(names, name+"=")
+			}
+		}
+		return start, names
+	}
+	return 0, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, t := range list {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// substitute does the ex command :s/pattern/replacement/flags,
+// on the cursor line, or with % in front, on every line. The
+// pattern is a Go regular expression. In the replacement, & or
+// \0 is the text that matched, and \1 to \9 are the groups.
+// With the g flag, it replaces every match on a line, not just
+// the first.
+func (e *Editor) substitute(cmd string) {
+	from, to := e.cy, e.cy
+	if strings.HasPrefix(cmd, "%") {
+		from, to = 0, e.numRows-1
+		cmd = cmd[1:]
+	}
+	parts := splitUnescaped(cmd[1:])
+	if len(parts) < 2 || parts[0] == "" {
+		e.SetStatusMessage("Want :s/pattern/replacement/")
+		return
+	}
+	flags := ""
+	if len(parts) > 2 {
+		flags = parts[2]
+	}
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		e.SetStatusMessage("Bad pattern: %s", err)
+		return
+	}
+	template := exTemplate(parts[1])
+	global := strings.Contains(flags, "g")
+
+	count, lines, last := 0, 0, -1
+	for y := from; y <= to && y < e.numRows; y++ {
+		text := row.Bytes(e.rows[y].Chars)
+		var out []byte
+		done, matched := 0, false
+		for _, m := range re.FindAllSubmatchIndex(text, -1) {
+			out = append(out, text[done:m[0]]...)
+			out = re.Expand(out, template, text, m)
+			done, matched = m[1], true
+			count++
+			if !global {
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		out = append(out, text[done:]...)
+		e.changeRow(y, func(r *row.Row) { setChars(r, row.Runes(out)) })
+		lines++
+		last = y
+	}
+	if count == 0 {
+		e.SetStatusMessage("Pattern not found: %s", parts[0])
+		return
+	}
+	e.Dirty = true
+	e.cx, e.cy = e.firstNonBlank(last), last
+	e.SetStatusMessage("%d substitutions on %d lines", count, lines)
+}
+
+// splitUnescaped splits s at the delimiter that starts it,
+// leaving out the first one, and skipping any delimiters with
+// a backslash in front.
+func splitUnescaped(s string) []string {
+	if s == "" {
+		return nil
+	}
+	delim := s[0]
+	var parts []string
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == delim:
+			b.WriteByte(delim)
+			i++
+		case s[i] == delim:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(parts, b.String())
+}
+
+// exTemplate turns a vi replacement into a template for
+// regexp.Expand.
+func exTemplate(repl string) []byte {
+	var t []byte
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		switch {
+		case c == '\\' && i+1 < len(repl):
+			i++
+			if d := repl[i]; d >= '0' && d <= '9' {
+				t = append(t, '$', '{', d, '}')
+			} else if d == '$' {
+				t = append(t, '$', '$')
+			} else {
+				t = append(t, d)
+			}
+		case c == '&':
+			t = append(t, "${0}"...)
+		case c == '$':
+			t = append(t, '$', '$')
+		default:
+			t = append(t, c)
+		}
+	}
+	return t
+}
@@ -0,0 +1,68 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoKilo/filemgt"
+)
+
+func TestWriteCopy(t *testing.T) {
+	e, name := openTestFile(t)
+	if !e.CheckSwapFiles() {
+		t.Fatal("CheckSwapFiles wants to quit")
+	}
+	u := e.beginUndoStep()
+	e.insertChar('x')
+	e.endUndoStep(u)
+
+	other := filepath.Join(filepath.Dir(name), "other.txt")
+	if _, err := e.runEx("w " + other); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(other); err != nil || string(got) != "xone\n" {
+		t.Errorf("other holds %q, %v, want \"xone\\n\"", got, err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "one\n" {
+		t.Errorf("file itself holds %q, want it unsaved", got)
+	}
+	if e.Filename != name || !e.Dirty {
+		t.Errorf("buffer is %q, dirty %v, want %q still unsaved", e.Filename, e.Dirty, name)
+	}
+	if info, _ := readSwap(t, name); !info.Mine() {
+		t.Error("buffer's swap file gone after writing a other")
+	}
+
+	if err := os.WriteFile(other, []byte("keep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e.runEx("w " + other)
+	if got, _ := os.ReadFile(other); string(got) != "keep\n" {
+		t.Errorf("existing file overwritten without !: holds %q", got)
+	}
+	e.runEx("w! " + other)
+	if got, _ := os.ReadFile(other); string(got) != "xone\n" {
+		t.Errorf("existing file not overwritten with !: holds %q", got)
+	}
+	e.removeSwaps()
+	if _, err := os.Stat(filemgt.SwapName(other)); !os.IsNotExist(err) {
+		t.Errorf("swap file left for the other: %v", err)
+	}
+}
+
+func TestWriteNamesNewBuffer(t *testing.T) {
+	e := newEditor(24, 80)
+	e.NewBuffer("")
+	e.AppendRow([]byte("hello"))
+	name := filepath.Join(t.TempDir(), "new.txt")
+	if _, err := e.runEx("w " + name); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "hello\n" {
+		t.Errorf("file holds %q, want \"hello\\n\"", got)
+	}
+	if e.Filename != name || e.Dirty {
+		t.Errorf("buffer is %q, dirty %v, want %q saved", e.Filename, e.Dirty, name)
+	}
+}
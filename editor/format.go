@@ -0,0 +1,52 @@
+package editor
+
+import (
+	"GoKilo/row"
+)
+
+/*** file format ***/
+
+// formatCommand changes how the current buffer's lines
+// get laid out on disk when it's saved.
+func (e *Editor) formatCommand() {
+	e.SetStatusMessage("Format %s: (l)f | (c)rlf | toggle (b)om | toggle final (n)ewline", e.Format)
+	e.RefreshScreen()
+	c, err := e.readKey()
+	if err != nil {
+		return
+	}
+	switch c {
+	case 'l', 'L':
+		e.setLineEndings(false)
+	case 'c', 'C':
+		e.setLineEndings(true)
+	case 'b', 'B':
+		e.Format.BOM = !e.Format.BOM
+	case 'n', 'N':
+		e.Format.FinalNewline = !e.Format.FinalNewline
+	default:
+		e.SetStatusMessage("")
+		return
+	}
+	e.Dirty = true
+	e.SetStatusMessage("Format is now %s", e.Format)
+}
+
+// setLineEndings makes every line end in "\r\n" if crlf is true,
+// or in "\n" if it's false. Lines from a file with mixed line
+// endings lose the "\r" they kept as part of their text.
+func (e *Editor) setLineEndings(crlf bool) {
+	if e.Format.Mixed {
+		for i, r := range e.rows {
+			if r.Size > 0 && r.Chars[r.Size-1] == '\r' {
+				e.changeRow(i, func(r *row.Row) {
+					r.Chars = r.Chars[:r.Size-1]
+					r.Size--
+					r.UpdateRow()
+				})
+			}
+		}
+	}
+	e.Format.CRLF = crlf
+	e.Format.Mixed = false
+}
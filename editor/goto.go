@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GoKilo/row"
+)
+
+/*** go to ***/
+
+// parsePosition reads a position typed at the go to prompt:
+// line, line:col, or +N or -N lines from line cy. Lines and
+// columns count from 1, and col is 0 if it isn't given.
+func parsePosition(s string, cy int) (line, col int, err error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	line, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad line number %q", parts[0])
+	}
+	if parts[0][0] == '+' || parts[0][0] == '-' {
+		line += cy + 1
+	}
+	if len(parts) == 2 {
+		col, err = strconv.Atoi(parts[1])
+		if err != nil || col < 1 {
+			return 0, 0, fmt.Errorf("bad column number %q", parts[1])
+		}
+	}
+	return line, col, nil
+}
+
+// byteColumnToCx finds the position in r of byte column col,
+// counting from 1, which is how compilers report columns.
+// The position is the start of a grapheme.
+func byteColumnToCx(r *row.Row, col int) int {
+	b := 0
+	cx := 0
+	for cx < r.Size {
+		next := r.NextGrapheme(cx)
+		b += len(row.Bytes(r.Chars[cx:next]))
+		if b >= col {
+			break
+		}
+		cx = next
+	}
+	return cx
+}
+
+// GotoPosition moves the cursor to line and byte column col,
+// both counting from 1, and puts that line in the middle of
+// the window. A col of 0 means the start of the line.
+func (e *Editor) GotoPosition(line, col int) {
+	e.cy = clamp(line-1, 0, e.numRows-1)
+	e.cx = 0
+	if col > 0 && e.cy < e.numRows {
+		e.cx = byteColumnToCx(e.rows[e.cy], col)
+	}
+	e.rowoff = clamp(e.cy-e.screenRows/2, 0, e.cy)
+}
+
+func (e *Editor) gotoPrompt() {
+	where, err := e.prompt("Go to line[:col] or +N/-N: %s (ESC to cancel)", lineOptions{history: "goto"})
+	if err != nil || where == "" {
+		return
+	}
+	line, col, err := parsePosition(where, e.cy)
+	if err != nil {
+		e.SetStatusMessage("%s", err)
+		return
+	}
+	e.GotoPosition(line, col)
+}
@@ -0,0 +1,104 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*** gutter ***/
+
+type gutterMode int
+
+const (
+	gutterOff gutterMode = iota
+	gutterAbsolute
+	gutterRelative
+	gutterHybrid
+)
+
+func (m gutterMode) String() string {
+	switch m {
+	case gutterAbsolute:
+		return "absolute"
+	case gutterRelative:
+		return "relative"
+	case gutterHybrid:
+		return "hybrid"
+	}
+	return "off"
+}
+
+// hitMatcher finds search hits to mark in the gutter while
+// the user types a search query.
+var hitMatcher matcher
+
+// gutterWidth is how many columns on the left of the current
+// window the gutter takes up: a marker column, enough digits
+// for the biggest line number, and a blank.
+func (e *Editor) gutterWidth() int {
+	if e.lineNumbers == gutterOff {
+		return 0
+	}
+	w := len(strconv.Itoa(e.numRows)) + 2
+	if w >= e.screenCols {
+		return 0
+	}
+	return w
+}
+
+// textCols is how many columns of the current window
+// are left over for the text of the file.
+func (e *Editor) textCols() int {
+	return e.screenCols - e.gutterWidth()
+}
+
+// marker picks the character to show in the gutter for
+// line filerow: '*' if it has a search hit, '+' if it's
+// changed since the file got read or saved.
+func (e *Editor) marker(filerow int) byte {
+	r := e.rows[filerow]
+	if hitMatcher != nil {
+		if x, _ := hitMatcher(r.Chars, 0); x > -1 {
+			return '*'
+		}
+	}
+	if r.Modified {
+		return '+'
+	}
+	return ' '
+}
+
+// drawGutter draws the gutter for line filerow of the current
+// window, or a blank gutter if filerow is past the end of file.
+func (e *Editor) drawGutter(ab *bytes.Buffer, filerow int) {
+	gw := e.gutterWidth()
+	if gw == 0 {
+		return
+	}
+	if filerow >= e.numRows {
+		ab.WriteString(strings.Repeat(" ", gw))
+		return
+	}
+	n := filerow + 1
+	switch {
+	case e.lineNumbers == gutterRelative, e.lineNumbers == gutterHybrid && filerow != e.cy:
+		n = filerow - e.cy
+		if n < 0 {
+			n = -n
+		}
+	}
+	ab.WriteByte(e.marker(filerow))
+	if filerow != e.cy {
+		ab.WriteString("\x1b[90m")
+	}
+	ab.WriteString(fmt.Sprintf("%*d ", gw-2, n))
+	ab.WriteString("\x1b[39m")
+}
+
+// cycleLineNumbers steps through the line number modes.
+func (e *Editor) cycleLineNumbers() {
+	e.lineNumbers = (e.lineNumbers + 1) % (gutterHybrid + 1)
+	e.SetStatusMessage("Line numbers: %s", e.lineNumbers)
+}
@@ -0,0 +1,38 @@
+package editor
+
+import (
+	"GoKilo/row"
+)
+
+/*** indentation ***/
+
+// leadingSpace returns the spaces and tabs at the start of chars.
+func leadingSpace(chars []rune) []rune {
+	n := 0
+	for n < len(chars) && (chars[n] == ' ' || chars[n] == '\t') {
+		n++
+	}
+	return chars[:n]
+}
+
+// typeChar inserts c as the user types it, taking a level of
+// indentation off the line if c closes something at its start.
+func (e *Editor) typeChar(c rune) {
+	if c == '\t' && e.expandTab {
+		e.insertTab()
+		return
+	}
+	if e.cy < e.numRows && e.syntax.DedentsOn(c) {
+		chars := e.rows[e.cy].Chars
+		space := leadingSpace(chars)
+		unit := e.indentUnit()
+		n := len(space) - len(unit)
+		if e.cx == len(space) && n >= 0 && string(space[n:]) == string(unit) {
+			e.changeRow(e.cy, func(r *row.Row) {
+				setChars(r, append(copyRunes(space[:n]), chars[e.cx:]...))
+			})
+			e.cx = n
+		}
+	}
+	e.insertChar(c)
+}
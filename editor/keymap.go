@@ -0,0 +1,334 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"GoKilo/config"
+	"GoKilo/keyboard"
+)
+
+/*** keymap ***/
+
+// A keymap says what command each key runs. A key that starts
+// a chord, like Ctrl-X in Ctrl-X Ctrl-S, has its own keymap for
+// the key after it.
+type keymap struct {
+	commands map[int]*command
+	prefixes map[int]*keymap
+}
+
+func newKeymap() *keymap {
+	return &keymap{commands: map[int]*command{}, prefixes: map[int]*keymap{}}
+}
+
+// bind binds the chord keys to cmd, or unbinds it if cmd is
+// nil. Whatever the keys, or the start of them, did before, they
+// don't do any more.
+func (km *keymap) bind(keys []int, cmd *command) {
+	for _, k := range keys[:len(keys)-1] {
+		delete(km.commands, k)
+		next := km.prefixes[k]
+		if next == nil {
+			next = newKeymap()
+			km.prefixes[k] = next
+		}
+		km = next
+	}
+	last := keys[len(keys)-1]
+	delete(km.prefixes, last)
+	delete(km.commands, last)
+	if cmd != nil {
+		km.commands[last] = cmd
+	}
+}
+
+// each calls f with every chord in km and the command it's
+// bound to, in no particular order.
+func (km *keymap) each(f func(keys []int, cmd *command)) {
+	km.walk(nil, f)
+}
+
+func (km *keymap) walk(before []int, f func(keys []int, cmd *command)) {
+	for k, cmd := range km.commands {
+		f(append(before[:len(before):len(before)], k), cmd)
+	}
+	for k, next := range km.prefixes {
+		next.walk(append(before[:len(before):len(before)], k), f)
+	}
+}
+
+// A keyBinding binds keys, by name, to a command, by name.
+type keyBinding struct {
+	keys, command string
+}
+
+// defaultKeys are the key bindings kilo starts with.
+var defaultKeys = []keyBinding{
+	{"Enter", "newline"},
+	{"Ctrl-Q", "quit"},
+	{"Ctrl-S", "save"},
+	{"F1", "help"},
+	{"Home", "line-start"},
+	{"End", "line-end"},
+	{"Ctrl-F", "find"},
+	{"Ctrl-G", "goto"},
+	{"Ctrl-K", "command"},
+	{"Ctrl-R", "replace"},
+	{"Ctrl-N", "next-buffer"},
+	{"Ctrl-P", "prev-buffer"},
+	{"Ctrl-B", "pick-buffer"},
+	{"Ctrl-W", "window"},
+	{"Ctrl-O", "line-numbers"},
+	{"Ctrl-E", "format"},
+	{"Ctrl-T", "tabs"},
+	{"Ctrl-Z", "undo"},
+	{"Ctrl-Y", "redo"},
+	{"Backspace", "delete-back"},
+	{"Ctrl-H", "delete-back"},
+	{"Delete", "delete"},
+	{"Ctrl-Backspace", "delete-word-back"},
+	{"Alt-Backspace", "delete-word-back"},
+	{"Ctrl-Delete", "delete-word"},
+	{"Ctrl-Left", "word-left"},
+	{"Ctrl-Right", "word-right"},
+	{"Ctrl-Home", "file-start"},
+	{"Ctrl-End", "file-end"},
+	{"PageUp", "page-up"},
+	{"PageDown", "page-down"},
+	{"Left", "left"},
+	{"Right", "right"},
+	{"Up", "up"},
+	{"Down", "down"},
+	{"Shift-Left", "select-left"},
+	{"Shift-Right", "select-right"},
+	{"Shift-Up", "select-up"},
+	{"Shift-Down", "select-down"},
+	{"Shift-Home", "select-line-start"},
+	{"Shift-End", "select-line-end"},
+	{"Ctrl-Space", "mark"},
+	{"Ctrl-C", "copy"},
+	{"Ctrl-X", "cut"},
+	{"Ctrl-V", "paste"},
+	{"Ctrl-U", "paste-system"},
+	{"Ctrl-L", "redraw"},
+}
+
+// emacsKeys are the key bindings for the emacs profile.
+var emacsKeys = []keyBinding{
+	{"Enter", "newline"},
+	{"Ctrl-X Ctrl-S", "save"},
+	{"Ctrl-X Ctrl-C", "quit"},
+	{"F1", "help"},
+	{"Ctrl-S", "find"},
+	{"Alt-%", "replace"},
+	{"Alt-g g", "goto"},
+	{"Alt-g Alt-g", "goto"},
+	{"Alt-x", "command"},
+	{"Ctrl-_", "undo"},
+	{"Ctrl-X u", "undo"},
+	{"Ctrl-Z", "undo"},
+	{"Alt-_", "redo"},
+	{"Ctrl-A", "line-start"},
+	{"Ctrl-E", "line-end"},
+	{"Home", "line-start"},
+	{"End", "line-end"},
+	{"Ctrl-F", "right"},
+	{"Ctrl-B", "left"},
+	{"Ctrl-N", "down"},
+	{"Ctrl-P", "up"},
+	{"Left", "left"},
+	{"Right", "right"},
+	{"Up", "up"},
+	{"Down", "down"},
+	{"Alt-f", "word-right"},
+	{"Alt-b", "word-left"},
+	{"Ctrl-Right", "word-right"},
+	{"Ctrl-Left", "word-left"},
+	{"Ctrl-V", "page-down"},
+	{"Alt-v", "page-up"},
+	{"PageDown", "page-down"},
+	{"PageUp", "page-up"},
+	{"Alt-<", "file-start"},
+	{"Alt->", "file-end"},
+	{"Ctrl-Home", "file-start"},
+	{"Ctrl-End", "file-end"},
+	{"Backspace", "delete-back"},
+	{"Ctrl-H", "delete-back"},
+	{"Ctrl-D", "delete"},
+	{"Delete", "delete"},
+	{"Alt-d", "kill-word"},
+	{"Alt-Backspace", "kill-word-back"},
+	{"Ctrl-Backspace", "kill-word-back"},
+	{"Ctrl-K", "kill-line"},
+	{"Ctrl-Space", "mark"},
+	{"Ctrl-W", "cut"},
+	{"Alt-w", "copy"},
+	{"Ctrl-Y", "yank"},
+	{"Alt-y", "yank-pop"},
+	{"Ctrl-G", "cancel"},
+	{"Shift-Left", "select-left"},
+	{"Shift-Right", "select-right"},
+	{"Shift-Up", "select-up"},
+	{"Shift-Down", "select-down"},
+	{"Shift-Home", "select-line-start"},
+	{"Shift-End", "select-line-end"},
+	{"Ctrl-X b", "pick-buffer"},
+	{"Ctrl-X Right", "next-buffer"},
+	{"Ctrl-X Left", "prev-buffer"},
+	{"Ctrl-X w", "window"},
+	{"Ctrl-X n", "line-numbers"},
+	{"Ctrl-X f", "format"},
+	{"Ctrl-X t", "tabs"},
+	{"Ctrl-X Ctrl-Y", "paste-system"},
+	{"Ctrl-L", "redraw"},
+}
+
+// profileKeys are the key bindings each profile starts with.
+// The vi profile starts with kilo's, for insert mode.
+var profileKeys = map[string][]keyBinding{
+	"kilo":  defaultKeys,
+	"vi":    defaultKeys,
+	"emacs": emacsKeys,
+}
+
+// makeKeymap returns the keymap for profile with bindings on top.
+func makeKeymap(profile string, bindings []config.Binding) (*keymap, error) {
+	km := newKeymap()
+	for _, b := range profileKeys[profile] {
+		keys, err := keyboard.ParseKeys(b.keys)
+		if err != nil {
+			panic(err)
+		}
+		km.bind(keys, commandNamed(b.command))
+	}
+	var first error
+	for _, b := range bindings {
+		cmd := commandNamed(b.Command)
+		if cmd == nil && b.Command != "" {
+			if first == nil {
+				first = fmt.Errorf("no command called %q", b.Command)
+				if b.Where != "" {
+					first = fmt.Errorf("%s: %s", b.Where, first)
+				}
+			}
+			continue
+		}
+		km.bind(b.Keys, cmd)
+	}
+	return km, first
+}
+
+// readCommand reads keys until they make up a chord, and
+// returns the command it's bound to, or nil if it isn't bound
+// to one, along with the last key.
+func (e *Editor) readCommand() (*command, int, error) {
+	c, err := e.waitKey(e.idle)
+	if err != nil {
+		return nil, c, err
+	}
+	return e.commandFor(c)
+}
+
+// commandFor does the work of readCommand once the first
+// key, c, is in.
+func (e *Editor) commandFor(c int) (*command, int, error) {
+	km := e.keys
+	var chord []int
+	for {
+		if cmd := km.commands[c]; cmd != nil {
+			if len(chord) > 0 {
+				e.SetStatusMessage("")
+			}
+			return cmd, c, nil
+		}
+		next := km.prefixes[c]
+		chord = append(chord, c)
+		if next == nil {
+			if len(chord) > 1 {
+				e.SetStatusMessage("%s isn't bound to anything", keyboard.KeysName(chord))
+				return nil, keyboard.UNKNOWN, nil
+			}
+			return nil, c, nil
+		}
+		km = next
+		e.SetStatusMessage("%s-", keyboard.KeysName(chord))
+		e.RefreshScreen()
+		var err error
+		if c, err = e.readKey(); err != nil {
+			return nil, c, err
+		}
+	}
+}
+
+// keysFor returns the names of the keys bound to cmd, shortest
+// first.
+func (e *Editor) keysFor(cmd *command) []string {
+	var names []string
+	e.keys.each(func(keys []int, c *command) {
+		if c == cmd {
+			names = append(names, keyboard.KeysName(keys))
+		}
+	})
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// keyFor returns the name of the first key bound to the command
+// called name, or if no key is, the name of the command.
+func (e *Editor) keyFor(name string) string {
+	if keys := e.keysFor(commandNamed(name)); len(keys) > 0 {
+		return keys[0]
+	}
+	return name
+}
+
+// KeyHelp returns a line saying which keys do the things
+// people need to know first, as many as fit on the screen.
+func (e *Editor) KeyHelp() string {
+	help := "HELP:"
+	sep := " "
+	for _, what := range []string{"help", "save", "quit", "find", "undo"} {
+		keys := e.keysFor(commandNamed(what))
+		if len(keys) == 0 {
+			continue
+		}
+		if what == "help" {
+			what = "keys"
+		}
+		hint := sep + keys[0] + " = " + what
+		if len(help)+len(hint) > e.termCols {
+			break
+		}
+		help += hint
+		sep = " | "
+	}
+	return help
+}
+
+// showKeys lists the commands, and the keys bound to them, in a
+// buffer of their own, made the first time and refilled after.
+func (e *Editor) showKeys() {
+	if e.keyList == nil {
+		e.NewBuffer("")
+		e.keyList = e.buffer
+	} else {
+		e.showBuffer(e.keyList)
+		e.rows, e.numRows = nil, 0
+		e.undoLog = undoLog{}
+		e.view = view{}
+	}
+	e.AppendRow([]byte(fmt.Sprintf("%-24s %-18s %s", "KEYS", "COMMAND", "WHAT IT DOES")))
+	for _, cmd := range commands {
+		keys := strings.Join(e.keysFor(cmd), ", ")
+		e.AppendRow([]byte(fmt.Sprintf("%-24s %-18s %s", keys, cmd.name, cmd.help)))
+	}
+	e.Dirty = false
+	e.SetStatusMessage("Key bindings. %s to go back.", e.keyFor("prev-buffer"))
+}
@@ -0,0 +1,66 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"GoKilo/config"
+	"GoKilo/keyboard"
+)
+
+func TestShowKeysReusesItsBuffer(t *testing.T) {
+	e := newEditor(24, 80)
+	e.NewBuffer("")
+	e.AppendRow([]byte("text"))
+	file := e.buffer
+
+	e.showKeys()
+	list, lines := e.buffer, e.numRows
+	e.showBuffer(file)
+	e.showKeys()
+	if n := len(e.buffers); n != 2 {
+		t.Errorf("%d buffers after showing the keys twice, want 2", n)
+	}
+	if e.buffer != list {
+		t.Error("showing the keys again made a new buffer")
+	}
+	if e.numRows != lines {
+		t.Errorf("key list has %d lines the second time, %d the first", e.numRows, lines)
+	}
+	if e.Dirty {
+		t.Error("key list has unsaved changes")
+	}
+}
+
+func TestBuiltInKeys(t *testing.T) {
+	for _, table := range [][]keyBinding{defaultKeys, emacsKeys} {
+		for _, b := range table {
+			if _, err := keyboard.ParseKeys(b.keys); err != nil {
+				t.Errorf("%q: %s", b.keys, err)
+			}
+			if commandNamed(b.command) == nil {
+				t.Errorf("%q is bound to %q, which is no command", b.keys, b.command)
+			}
+		}
+	}
+}
+
+func TestMessagesNameBoundKeys(t *testing.T) {
+	e := newEditor(24, 80)
+	s := config.Default()
+	s.Profile = "emacs"
+	if err := e.Configure(s); err != nil {
+		t.Fatal(err)
+	}
+	e.NewBuffer("")
+	e.Dirty = true
+	if again, _ := e.processQuit(); !again {
+		t.Fatal("quit with unsaved changes")
+	}
+	if !strings.Contains(e.statusmsg, "Press Ctrl-X Ctrl-C ") {
+		t.Errorf("quit warning %q doesn't name the emacs quit key", e.statusmsg)
+	}
+	if got := e.keyFor("prev-buffer"); got != "Ctrl-X Left" {
+		t.Errorf("keyFor(prev-buffer) = %q, want Ctrl-X Left", got)
+	}
+}
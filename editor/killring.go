@@ -0,0 +1,138 @@
+package editor
+
+import (
+	"strings"
+)
+
+/*** kill ring ***/
+
+// killRingSize is how many kills the kill ring keeps.
+const killRingSize = 60
+
+// killRing holds text that got killed, newest first, so Alt-Y
+// can go back to older kills after a yank.
+type killRing struct {
+	kills    [][][]rune
+	yanked   int  // which kill the last yank put in
+	x, y     int  // where the last yank started
+	inserted bool // the last yank put text in, from x, y to the cursor
+}
+
+// kill puts text on the kill ring, and on the clipboard. If the
+// command before was a kill too, text goes with what it killed,
+// in front of it if backward is true.
+func (e *Editor) kill(text [][]rune, backward bool) {
+	k := &e.killRing
+	if len(k.kills) > 0 && e.lastCommand != nil && e.lastCommand.kills {
+		if backward {
+			text = joinText(text, k.kills[0])
+		} else {
+			text = joinText(k.kills[0], text)
+		}
+		k.kills[0] = text
+	} else {
+		k.kills = append([][][]rune{text}, k.kills...)
+		if len(k.kills) > killRingSize {
+			k.kills = k.kills[:killRingSize]
+		}
+	}
+	e.clipboard = text
+	if err := e.exportClipboard(); err != nil {
+		e.SetStatusMessage("Not copied to the system clipboard: %s", err)
+	}
+}
+
+// joinText returns text a with text b on the end of it.
+func joinText(a, b [][]rune) [][]rune {
+	if len(a) == 0 {
+		return b
+	}
+	last := len(a) - 1
+	text := append([][]rune(nil), a[:last]...)
+	text = append(text, append(copyRunes(a[last]), b[0]...))
+	return append(text, b[1:]...)
+}
+
+// sameText reports whether a and b hold the same text.
+func sameText(a, b [][]rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if string(a[i]) != string(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// killLine kills the rest of the line, or the line end, if
+// there's only blank space before it.
+func (e *Editor) killLine() {
+	if e.cy >= e.numRows {
+		return
+	}
+	chars := e.rows[e.cy].Chars
+	ex, ey := len(chars), e.cy
+	if strings.TrimSpace(string(chars[e.cx:])) == "" && e.cy+1 < e.numRows {
+		ex, ey = 0, e.cy+1
+	}
+	if ex == e.cx && ey == e.cy {
+		return
+	}
+	e.kill(e.textBetween(e.cx, e.cy, ex, ey), false)
+	e.deleteBetween(e.cx, e.cy, ex, ey)
+}
+
+// killWord kills from the cursor to where moveWord would go.
+func (e *Editor) killWord(dir int) {
+	sx, sy, ex, ey := e.wordRange(dir)
+	if sx == ex && sy == ey {
+		return
+	}
+	e.kill(e.textBetween(sx, sy, ex, ey), dir < 0)
+	e.deleteBetween(sx, sy, ex, ey)
+}
+
+// yank puts in the newest kill, or whatever else is on the
+// clipboard, at the cursor.
+func (e *Editor) yank() {
+	k := &e.killRing
+	k.inserted = false
+	if len(e.clipboard) == 0 {
+		e.SetStatusMessage("Kill ring is empty")
+		return
+	}
+	if len(k.kills) == 0 || !sameText(k.kills[0], e.clipboard) {
+		// Copied some other way, so not on the ring yet.
+		k.kills = append([][][]rune{e.clipboard}, k.kills...)
+	}
+	k.yanked = 0
+	k.x, k.y = e.cx, e.cy
+	e.insertText(k.kills[0])
+	k.inserted = true
+}
+
+// yankPop replaces the text the last yank put in with the kill
+// before it on the ring.
+func (e *Editor) yankPop() {
+	k := &e.killRing
+	if e.lastCommand == nil || (e.lastCommand.name != "yank" && e.lastCommand.name != "yank-pop") || !k.inserted {
+		e.SetStatusMessage("Previous command was not a yank")
+		return
+	}
+	if len(k.kills) < 2 {
+		e.SetStatusMessage("No older kills")
+		return
+	}
+	e.deleteBetween(k.x, k.y, e.cx, e.cy)
+	k.yanked = (k.yanked + 1) % len(k.kills)
+	e.insertText(k.kills[k.yanked])
+	e.SetStatusMessage("Kill %d of %d", k.yanked+1, len(k.kills))
+}
+
+// cancel drops the selection, and whatever else is going on.
+func (e *Editor) cancel() {
+	e.mark = mark{}
+	e.SetStatusMessage("Quit")
+}
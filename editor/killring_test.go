@@ -0,0 +1,47 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// runCommands runs the commands called names one after another,
+// the way ProcessKeypress would for their keys.
+func runCommands(t *testing.T, e *Editor, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		cmd := commandNamed(name)
+		if cmd == nil {
+			t.Fatalf("no command called %q", name)
+		}
+		u := e.beginUndoStep()
+		e.lastCommand, e.command = e.command, nil
+		_, err := e.runCommand(cmd)
+		e.endUndoStep(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestYankPop(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		commands []string
+		want     []string
+	}{
+		{"nothing killed", []string{"yank", "yank-pop"}, []string{"hello world"}},
+		{"one kill", []string{"kill-word", "yank", "yank-pop"}, []string{"hello world"}},
+		{"two kills", []string{"kill-word", "line-end", "yank", "word-left", "kill-word", "yank", "yank-pop"},
+			[]string{" hello"}},
+		{"not after a yank", []string{"kill-word", "line-end", "yank-pop"}, []string{" world"}},
+	} {
+		e := newEditor(24, 80)
+		e.NewBuffer("")
+		e.AppendRow([]byte("hello world"))
+		runCommands(t, e, tt.commands...)
+		if got := e.bufferLines(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: lines = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package editor
+
+import (
+	"strings"
+
+	"GoKilo/keyboard"
+	"GoKilo/row"
+)
+
+/*** replace ***/
+
+// replace prompts for a search string and its replacement, then
+// steps through the matches from the cursor onward, wrapping around
+// the end of the file, asking what to do with each one. The whole
+// command is a single undo step.
+func (e *Editor) replace() {
+	query, ok, err := e.readLine(promptFormat("Replace: %s (ESC to cancel)"), lineOptions{history: "search"})
+	if err != nil || !ok {
+		e.SetStatusMessage("Replace aborted")
+		return
+	}
+	with, ok, err := e.readLine(promptFormat("Replace "+strings.ReplaceAll(query, "%", "%%")+" with: %s (ESC to cancel)"), lineOptions{history: "replace", allowEmpty: true})
+	if err != nil || !ok {
+		e.SetStatusMessage("Replace aborted")
+		return
+	}
+	qry, repl := []rune(query), []rune(with)
+
+	startY, startX := e.cy, e.cx
+	y, x := startY, startX
+	wrapped := false
+	all := false
+	count := 0
+
+loop:
+	for {
+		if y >= e.numRows {
+			if wrapped {
+				break
+			}
+			y, x, wrapped = 0, 0, true
+			continue
+		}
+		idx := -1
+		if x <= e.rows[y].Size {
+			if i := row.Index(e.rows[y].Chars[x:], qry); i >= 0 {
+				idx = x + i
+			}
+		}
+		if wrapped && (y > startY || (y == startY && (idx < 0 || idx >= startX))) {
+			break
+		}
+		if idx < 0 {
+			y++
+			x = 0
+			continue
+		}
+
+		if !all {
+			e.cy, e.cx = y, idx
+			e.rowoff = e.numRows
+			e.highlightMatch(y, idx, len(qry))
+			e.SetStatusMessage("Replace this match? (y)es (n)o (a)ll (q)uit")
+			e.RefreshScreen()
+			c, err := e.readKey()
+			e.restoreHighlight()
+			if err != nil {
+				break
+			}
+			switch c {
+			case 'y', 'Y':
+			case 'a', 'A':
+				all = true
+			case 'n', 'N':
+				x = idx + len(qry)
+				continue
+			case 'q', 'Q', keyboard.ESCAPE:
+				break loop
+			default:
+				continue
+			}
+		}
+
+		e.replaceAt(y, idx, len(qry), repl)
+		count++
+		if wrapped && y == startY {
+			startX += len(repl) - len(qry)
+		}
+		x = idx + len(repl)
+		e.cy, e.cx = y, x
+	}
+
+	switch count {
+	case 1:
+		e.SetStatusMessage("1 replacement made")
+	default:
+		e.SetStatusMessage("%d replacements made", count)
+	}
+}
+
+// replaceAt puts repl in place of the length runes at position
+// cx of line at.
+func (e *Editor) replaceAt(at, cx, length int, repl []rune) {
+	e.changeRow(at, func(r *row.Row) {
+		chars := make([]rune, 0, r.Size-length+len(repl))
+		chars = append(chars, r.Chars[:cx]...)
+		chars = append(chars, repl...)
+		r.Chars = append(chars, r.Chars[cx+length:]...)
+		r.Size = len(r.Chars)
+		r.UpdateRow()
+	})
+}
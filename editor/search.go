@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"GoKilo/row"
+)
+
+/*** search modes ***/
+
+type searchMode int
+
+const (
+	searchLiteral searchMode = iota
+	searchIgnoreCase
+	searchRegexp
+)
+
+func (m searchMode) String() string {
+	switch m {
+	case searchIgnoreCase:
+		return "nocase"
+	case searchRegexp:
+		return "regexp"
+	}
+	return "literal"
+}
+
+// matcher finds the first match in chars that starts at or after
+// position from, returning the start and end positions of the
+// match in chars, or -1, -1 if there isn't one.
+type matcher func(chars []rune, from int) (int, int)
+
+// newMatcher builds a matcher for qry. Only searchRegexp mode
+// can return an error, for a pattern that doesn't compile.
+func newMatcher(qry []rune, mode searchMode) (matcher, error) {
+	switch mode {
+	case searchIgnoreCase:
+		return func(chars []rune, from int) (int, int) {
+			for i := from; i+len(qry) <= len(chars); i++ {
+				if equalFold(chars[i:i+len(qry)], qry) {
+					return i, i + len(qry)
+				}
+			}
+			return -1, -1
+		}, nil
+	case searchRegexp:
+		re, err := regexp.Compile(string(qry))
+		if err != nil {
+			return nil, err
+		}
+		return func(chars []rune, from int) (int, int) {
+			s := string(chars)
+			for _, m := range re.FindAllStringIndex(s, -1) {
+				start := utf8.RuneCountInString(s[:m[0]])
+				if start >= from {
+					return start, start + utf8.RuneCountInString(s[m[0]:m[1]])
+				}
+			}
+			return -1, -1
+		}, nil
+	}
+	return func(chars []rune, from int) (int, int) {
+		if from > len(chars) {
+			return -1, -1
+		}
+		if i := row.Index(chars[from:], qry); i >= 0 {
+			return from + i, from + i + len(qry)
+		}
+		return -1, -1
+	}, nil
+}
+
+func equalFold(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] && unicode.ToLower(a[i]) != unicode.ToLower(b[i]) {
+			return false
+		}
+	}
+	return true
+}
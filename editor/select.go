@@ -0,0 +1,210 @@
+package editor
+
+import (
+	"strings"
+
+	"GoKilo/keyboard"
+	"GoKilo/row"
+)
+
+/*** selection ***/
+
+// mark is the end of the selection that stays put while the
+// cursor, the other end, moves.
+type mark struct {
+	set     bool
+	shifted bool // set by a shifted arrow key, so plain motion drops it
+	cx, cy  int
+}
+
+// setMark starts a selection at the cursor, or drops the one
+// there is.
+func (e *Editor) setMark() {
+	if e.mark.set && !e.mark.shifted {
+		e.mark = mark{}
+		e.SetStatusMessage("Mark cleared")
+		return
+	}
+	e.mark = mark{set: true, cx: e.cx, cy: e.cy}
+	e.SetStatusMessage("Mark set")
+}
+
+// keepMark drops a selection made with shifted arrow keys when
+// cmd is anything but one of the commands that work on it.
+func (e *Editor) keepMark(cmd *command) {
+	if e.mark.shifted && (cmd == nil || !cmd.selects) {
+		e.mark = mark{}
+	}
+}
+
+// shiftMove moves the cursor for shifted arrow key c, selecting
+// the text it moves over.
+func (e *Editor) shiftMove(c int) {
+	if !e.mark.set {
+		e.mark = mark{set: true, shifted: true, cx: e.cx, cy: e.cy}
+	}
+	switch c {
+	case keyboard.SHIFT_LEFT:
+		e.moveCursor(keyboard.ARROW_LEFT)
+	case keyboard.SHIFT_RIGHT:
+		e.moveCursor(keyboard.ARROW_RIGHT)
+	case keyboard.SHIFT_UP:
+		e.moveCursor(keyboard.ARROW_UP)
+	case keyboard.SHIFT_DOWN:
+		e.moveCursor(keyboard.ARROW_DOWN)
+	case keyboard.SHIFT_HOME:
+		e.cx = 0
+	case keyboard.SHIFT_END:
+		if e.cy < e.numRows {
+			e.cx = e.rows[e.cy].Size
+		}
+	}
+}
+
+// selection returns the start and end of the selected text, in
+// file order. The end is just past the last selected character.
+// It returns ok false if nothing is selected.
+func (e *Editor) selection() (sx, sy, ex, ey int, ok bool) {
+	if !e.mark.set {
+		return
+	}
+	mx, my := e.mark.cx, clamp(e.mark.cy, 0, e.numRows)
+	if my == e.numRows {
+		mx = 0
+	} else {
+		mx = clamp(mx, 0, e.rows[my].Size)
+	}
+	sx, sy, ex, ey = mx, my, e.cx, e.cy
+	if ey < sy || (ey == sy && ex < sx) {
+		sx, sy, ex, ey = ex, ey, sx, sy
+	}
+	return sx, sy, ex, ey, sy != ey || sx != ex
+}
+
+// selectedRender returns the part of Render for file row filerow
+// that's selected, as from and to indexes.
+func (e *Editor) selectedRender(filerow int) (from, to int) {
+	sx, sy, ex, ey, ok := e.selection()
+	if !ok || filerow < sy || filerow > ey {
+		return 0, 0
+	}
+	rw := e.rows[filerow]
+	if filerow > sy {
+		sx = 0
+	}
+	if filerow < ey {
+		ex = rw.Size
+	}
+	return rw.RenderIndex(sx), rw.RenderIndex(ex)
+}
+
+// textBetween returns the text from (sx, sy) up to (ex, ey),
+// one slice of runes per line.
+func (e *Editor) textBetween(sx, sy, ex, ey int) [][]rune {
+	var lines [][]rune
+	for y := sy; y <= ey; y++ {
+		var chars []rune
+		if y < e.numRows {
+			chars = e.rows[y].Chars
+		}
+		from, to := 0, len(chars)
+		if y == sy {
+			from = sx
+		}
+		if y == ey {
+			to = ex
+		}
+		lines = append(lines, copyRunes(chars[from:to]))
+	}
+	return lines
+}
+
+func setChars(r *row.Row, s []rune) {
+	r.Chars = s
+	r.Size = len(s)
+	r.UpdateRow()
+}
+
+// deleteBetween deletes the text from (sx, sy) up to (ex, ey),
+// and leaves the cursor where it was.
+func (e *Editor) deleteBetween(sx, sy, ex, ey int) {
+	var tail []rune
+	if ey < e.numRows {
+		tail = copyRunes(e.rows[ey].Chars[ex:])
+	}
+	if sy < e.numRows {
+		e.changeRow(sy, func(r *row.Row) { setChars(r, append(r.Chars[:sx], tail...)) })
+	}
+	for y := sy + 1; y <= ey && sy+1 < e.numRows; y++ {
+		e.delRow(sy + 1)
+	}
+	e.cx, e.cy = sx, sy
+}
+
+// insertText puts lines of text in at the cursor, the first
+// line joining the text before the cursor and the last line
+// joining the text after it, and leaves the cursor after it.
+// However much text there is, the highlighting only gets
+// updated once.
+func (e *Editor) insertText(lines [][]rune) {
+	if len(lines) == 0 {
+		return
+	}
+	if e.cy == e.numRows {
+		e.insertRows(e.numRows, [][]rune{nil})
+	}
+	cx, cy := e.cx, e.cy
+	tail := copyRunes(e.rows[cy].Chars[cx:])
+	e.editRow(cy, func(r *row.Row) { setChars(r, append(r.Chars[:cx], lines[0]...)) })
+	middle := make([][]rune, len(lines)-1)
+	for i, line := range lines[1:] {
+		middle[i] = copyRunes(line)
+	}
+	e.insertRows(cy+1, middle)
+	last := cy + len(lines) - 1
+	e.cx, e.cy = e.rows[last].Size, last
+	e.editRow(last, func(r *row.Row) { setChars(r, append(r.Chars, tail...)) })
+	e.updateSyntaxRange(cy, last)
+}
+
+func (e *Editor) copySelection() {
+	sx, sy, ex, ey, ok := e.selection()
+	if !ok {
+		e.SetStatusMessage("Nothing selected")
+		return
+	}
+	e.SetStatusMessage("Copied %d lines", ey-sy+1)
+	e.kill(e.textBetween(sx, sy, ex, ey), false)
+	e.mark = mark{}
+}
+
+func (e *Editor) cutSelection() {
+	sx, sy, ex, ey, ok := e.selection()
+	if !ok {
+		e.SetStatusMessage("Nothing selected")
+		return
+	}
+	e.SetStatusMessage("Cut %d lines", ey-sy+1)
+	e.kill(e.textBetween(sx, sy, ex, ey), false)
+	e.deleteBetween(sx, sy, ex, ey)
+	e.mark = mark{}
+}
+
+// pasteText inserts text that came from outside the editor.
+func (e *Editor) pasteText(text string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var lines [][]rune
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, row.Runes([]byte(line)))
+	}
+	e.insertText(lines)
+	e.SetStatusMessage("Pasted %d lines", len(lines))
+}
+
+func (e *Editor) paste() {
+	if len(e.clipboard) == 0 {
+		e.SetStatusMessage("Clipboard is empty")
+		return
+	}
+	e.insertText(e.clipboard)
+}
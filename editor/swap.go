@@ -0,0 +1,148 @@
+package editor
+
+import (
+	"os"
+
+	"GoKilo/filemgt"
+	"GoKilo/keyboard"
+)
+
+/*** swap files ***/
+
+// A buffer's swap file gets rewritten after this many edits, even
+// if the user never stops typing long enough for an idle write.
+const swapEvery = 200
+
+// writeSwap brings buffer b's swap file up to date, if there have
+// been edits since it was last written, or if force is true. A buffer
+// with no unsaved changes gets a swap file with nothing to recover,
+// which just says that this kilo has the file open.
+func (b *buffer) writeSwap(force bool) {
+	if b.Filename == "" || b.noSwap || (!force && b.swapped == b.changes) {
+		return
+	}
+	if filemgt.WriteSwap(b.Filename, b.Dirty, b.rowsToString) == nil {
+		b.swapped = b.changes
+	}
+}
+
+// removeSwap gets rid of buffer b's swap file, unless another
+// kilo owns it.
+func (b *buffer) removeSwap() {
+	if b.Filename != "" && !b.noSwap {
+		filemgt.RemoveSwap(b.Filename)
+	}
+}
+
+// idle runs while the user isn't typing.
+func (e *Editor) idle() {
+	for _, b := range e.buffers {
+		b.writeSwap(false)
+	}
+	e.checkDisk()
+}
+
+// removeSwaps gets rid of every buffer's swap file, when quitting.
+func (e *Editor) removeSwaps() {
+	for _, b := range e.buffers {
+		b.removeSwap()
+	}
+}
+
+// CheckSwapFiles looks for swap files left behind by a kilo that
+// crashed while editing the files in the buffers, and asks whether
+// to recover the unsaved changes from them. It also warns about files
+// that another kilo is editing right now. It returns false if the
+// user would rather quit than edit such a file.
+func (e *Editor) CheckSwapFiles() bool {
+	current := e.buffer
+	defer e.showBuffer(current)
+	for i, b := range e.buffers {
+		if b.Filename == "" {
+			continue
+		}
+		e.showBuffer(b)
+		if !e.checkSwap() {
+			for _, b := range e.buffers[:i] {
+				b.removeSwap()
+			}
+			return false
+		}
+		b.writeSwap(true)
+	}
+	return true
+}
+
+// checkSwap deals with any swap file for the current buffer.
+func (e *Editor) checkSwap() bool {
+	var lines []string
+	info, format, err := filemgt.ReadSwap(e.Filename, func(line []byte) {
+		lines = append(lines, string(line))
+	})
+	switch {
+	case os.IsNotExist(err) || info.Mine():
+		return true
+	case err != nil:
+		e.noSwap = true
+		e.SetStatusMessage("Can't use swap file %s: %s", filemgt.SwapName(e.Filename), err)
+		return true
+	case info.Running():
+		return e.askConcurrent(info)
+	case !info.Modified:
+		// A crash with nothing unsaved.
+		return true
+	}
+	b := e.buffer
+	diffed := false
+	for {
+		e.SetStatusMessage("Crash left unsaved %.16s (%s): (r)ecover | (d)iff | discard (x)",
+			e.Filename, info.ModTime.Format("Jan 2 15:04"))
+		e.RefreshScreen()
+		c, err := e.readKey()
+		if err != nil {
+			return true
+		}
+		switch c {
+		case 'r', 'R':
+			e.showBuffer(b)
+			u := e.beginUndoStep()
+			e.setLines(lines)
+			e.endUndoStep(u)
+			e.Format = format
+			e.Dirty = true
+			e.SetStatusMessage("Recovered %.20s. Save to keep the changes.", e.Filename)
+			return true
+		case 'd', 'D':
+			if !diffed {
+				e.showDiff(e.Filename, e.Filename+" (swap)", e.bufferLines(), lines)
+				diffed = true
+			}
+		case 'x', 'X':
+			e.showBuffer(b)
+			e.SetStatusMessage("Discarded unsaved changes to %.20s", e.Filename)
+			return true
+		}
+	}
+}
+
+// askConcurrent warns that another kilo has the current buffer's file
+// open. Editing it anyway means this kilo keeps no swap file for it.
+func (e *Editor) askConcurrent(info filemgt.SwapInfo) bool {
+	for {
+		e.SetStatusMessage("%.20s is open in kilo process %d on %s: (e)dit anyway | (q)uit",
+			e.Filename, info.PID, info.Host)
+		e.RefreshScreen()
+		c, err := e.readKey()
+		if err != nil {
+			return false
+		}
+		switch c {
+		case 'e', 'E':
+			e.noSwap = true
+			e.SetStatusMessage("Editing %.20s without a swap file", e.Filename)
+			return true
+		case 'q', 'Q', keyboard.CTRL_Q:
+			return false
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoKilo/filemgt"
+)
+
+// readSwap returns what the swap file for file name says, and the
+// lines of unsaved text in it.
+func readSwap(t *testing.T, name string) (filemgt.SwapInfo, []string) {
+	t.Helper()
+	var lines []string
+	info, _, err := filemgt.ReadSwap(name, func(line []byte) {
+		lines = append(lines, string(line))
+	})
+	if err != nil {
+		t.Fatalf("reading swap file: %s", err)
+	}
+	return info, lines
+}
+
+// writeSwapFile puts a swap file for file name there, as if kilo
+// process pid had written it.
+func writeSwapFile(t *testing.T, name string, pid int, modified bool) {
+	t.Helper()
+	host, _ := os.Hostname()
+	header := fmt.Sprintf("kilo swap file\npid %d\nhost %s\nmodified %t\n\n", pid, host, modified)
+	if err := os.WriteFile(filemgt.SwapName(name), []byte(header), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// typeKeys makes keys what the keyboard reads next, and sends
+// what the editor draws nowhere, for as long as the test runs.
+func typeKeys(t *testing.T, keys string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(keys)
+	w.Close()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = r, null
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = stdin, stdout
+		r.Close()
+		null.Close()
+	})
+}
+
+func openTestFile(t *testing.T) (*Editor, string) {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(name, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := newEditor(24, 80)
+	if err := e.OpenFile(name); err != nil {
+		t.Fatal(err)
+	}
+	return e, name
+}
+
+func TestSwapFileWhileOpen(t *testing.T) {
+	e, name := openTestFile(t)
+	if !e.CheckSwapFiles() {
+		t.Fatal("CheckSwapFiles wants to quit")
+	}
+	if info, _ := readSwap(t, name); !info.Mine() || info.Modified {
+		t.Errorf("after opening, swap file says %+v, want this kilo and nothing unsaved", info)
+	}
+
+	u := e.beginUndoStep()
+	e.insertChar('x')
+	e.endUndoStep(u)
+	e.writeSwap(false)
+	if info, lines := readSwap(t, name); !info.Modified || strings.Join(lines, "\n") != "xone" {
+		t.Errorf("after an edit, swap file says %+v, holding %q", info, lines)
+	}
+
+	e.writeFile()
+	if info, lines := readSwap(t, name); !info.Mine() || info.Modified || len(lines) > 0 {
+		t.Errorf("after saving, swap file says %+v, holding %q", info, lines)
+	}
+
+	e.removeSwaps()
+	if _, err := os.Stat(filemgt.SwapName(name)); !os.IsNotExist(err) {
+		t.Errorf("swap file still there after quitting: %v", err)
+	}
+}
+
+func TestSwapFileSpotsFileOpenTwice(t *testing.T) {
+	// The parent process stands in for another kilo, still
+	// running, that has the file open with nothing unsaved.
+	other := os.Getppid()
+
+	e, name := openTestFile(t)
+	writeSwapFile(t, name, other, false)
+	typeKeys(t, "q")
+	if e.CheckSwapFiles() {
+		t.Error("CheckSwapFiles didn't ask about the other kilo")
+	}
+	if info, _ := readSwap(t, name); info.PID != other {
+		t.Errorf("swap file is process %d's now, want the other kilo's, %d", info.PID, other)
+	}
+
+	e, name = openTestFile(t)
+	writeSwapFile(t, name, other, false)
+	typeKeys(t, "e")
+	if !e.CheckSwapFiles() {
+		t.Fatal("CheckSwapFiles wants to quit after (e)dit anyway")
+	}
+	u := e.beginUndoStep()
+	e.insertChar('x')
+	e.endUndoStep(u)
+	e.writeSwap(false)
+	e.removeSwaps()
+	if info, _ := readSwap(t, name); info.PID != other || info.Modified {
+		t.Errorf("swap file says %+v, want the other kilo's, untouched", info)
+	}
+}
+
+func TestLeftoverSwapFileReplaced(t *testing.T) {
+	// A swap file from a kilo that crashed with nothing unsaved.
+	e, name := openTestFile(t)
+	writeSwapFile(t, name, 1<<30, false)
+	if !e.CheckSwapFiles() {
+		t.Fatal("CheckSwapFiles wants to quit")
+	}
+	if info, _ := readSwap(t, name); !info.Mine() {
+		t.Errorf("leftover swap file from process %d still there", info.PID)
+	}
+}
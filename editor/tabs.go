@@ -0,0 +1,118 @@
+package editor
+
+import (
+	"strings"
+
+	"GoKilo/config"
+	"GoKilo/highlighter"
+	"GoKilo/row"
+)
+
+/*** tabs ***/
+
+// Configure makes s the editor's settings. Tab settings only
+// change for buffers opened from now on. If some of the key
+// bindings name commands there aren't, it uses the rest, and
+// returns an error about the first bad one.
+func (e *Editor) Configure(s config.Settings) error {
+	keys, err := makeKeymap(s.Profile, s.Keys)
+	e.settings = s
+	e.quitTimes = s.QuitTimes
+	e.keys = keys
+	if s.Profile != "vi" {
+		e.vi = nil
+	} else if e.vi == nil {
+		e.vi = &viState{}
+	}
+	return err
+}
+
+// filetypeTabs gives buffer b the tab settings for its filetype.
+func (e *Editor) filetypeTabs(b *buffer) {
+	b.tabStop, b.expandTab = e.settings.Tabs(highlighter.SelectSyntaxHighlight(b.Filename))
+}
+
+// setTabStop makes tabs in the current buffer n columns wide.
+func (e *Editor) setTabStop(n int) {
+	e.tabStop = n
+	for _, r := range e.rows {
+		r.TabStop = n
+		r.UpdateRow()
+	}
+	e.UpdateAllSyntax()
+}
+
+// indentUnit returns one level of indentation.
+func (e *Editor) indentUnit() []rune {
+	if e.expandTab {
+		return []rune(strings.Repeat(" ", e.tabStop))
+	}
+	return []rune{'\t'}
+}
+
+// insertTab puts in spaces up to the next tab stop.
+func (e *Editor) insertTab() {
+	rx := 0
+	if e.cy < e.numRows {
+		rx = e.rows[e.cy].RowCxToRx(e.cx)
+	}
+	for n := e.tabStop - rx%e.tabStop; n > 0; n-- {
+		e.insertChar(' ')
+	}
+}
+
+// deleteIndent deletes spaces back to the previous tab stop, if
+// tabs get expanded and there's nothing but spaces before the
+// cursor. It returns false if it didn't delete anything.
+func (e *Editor) deleteIndent() bool {
+	if !e.expandTab || e.cx == 0 || e.cy == e.numRows {
+		return false
+	}
+	chars := e.rows[e.cy].Chars
+	if strings.TrimLeft(string(chars[:e.cx]), " ") != "" {
+		return false
+	}
+	to := (e.cx - 1) / e.tabStop * e.tabStop
+	e.changeRow(e.cy, func(r *row.Row) {
+		setChars(r, append(copyRunes(chars[:to]), chars[e.cx:]...))
+	})
+	e.cx = to
+	return true
+}
+
+// tabCommand changes the current buffer's tab settings.
+func (e *Editor) tabCommand() {
+	how := "tabs"
+	if e.expandTab {
+		how = "spaces"
+	}
+	e.SetStatusMessage("Tab width %d, indent with %s: tab (w)idth | toggle (s)paces", e.tabStop, how)
+	e.RefreshScreen()
+	c, err := e.readKey()
+	if err != nil {
+		return
+	}
+	switch c {
+	case 'w', 'W':
+		width, err := e.prompt("Tab width: %s (ESC to cancel)", lineOptions{})
+		if err != nil || width == "" {
+			return
+		}
+		s := e.settings
+		if err := s.Set("tabstop", width); err != nil {
+			e.SetStatusMessage("%s", err)
+			return
+		}
+		e.setTabStop(s.TabStop)
+		e.SetStatusMessage("Tab width is now %d", e.tabStop)
+	case 's', 'S':
+		e.expandTab = !e.expandTab
+		if e.expandTab {
+			e.SetStatusMessage("Tab now indents with spaces")
+		} else {
+			e.SetStatusMessage("Tab now indents with tabs")
+		}
+	default:
+		e.SetStatusMessage("")
+	}
+}
@@ -0,0 +1,215 @@
+package editor
+
+/*** undo ***/
+
+type editKind int
+
+const (
+	editInsertRow editKind = iota
+	editDeleteRow
+	editChangeRow
+)
+
+// edit records a single change to the rows of the file under
+// edit, with enough information to reverse it or do it again.
+type edit struct {
+	kind   editKind
+	at     int
+	before []rune
+	after  []rune
+}
+
+// undoStep holds all the edits one command made, plus where
+// the cursor was before and after, so that a single undo
+// reverses the whole command.
+type undoStep struct {
+	edits    []edit
+	cx, cy   int
+	ecx, ecy int
+	typing   bool
+}
+
+// undoLog keeps the undo and redo stacks. Edits only get recorded
+// while a step is open, so loading a file doesn't fill it up.
+type undoLog struct {
+	done      []*undoStep
+	undone    []*undoStep
+	current   *undoStep
+	depth     int
+	replaying bool
+	savedAt   *undoStep
+}
+
+func copyRunes(s []rune) []rune {
+	return append(make([]rune, 0, len(s)), s...)
+}
+
+func (u *undoLog) top() *undoStep {
+	if len(u.done) == 0 {
+		return nil
+	}
+	return u.done[len(u.done)-1]
+}
+
+func (u *undoLog) record(ed edit) {
+	if u.current == nil || u.replaying {
+		return
+	}
+	if n := len(u.current.edits); n > 0 && ed.kind == editChangeRow {
+		last := &u.current.edits[n-1]
+		if last.kind == editChangeRow && last.at == ed.at {
+			last.after = ed.after
+			return
+		}
+	}
+	u.current.edits = append(u.current.edits, ed)
+}
+
+// beginUndoStep opens a new undo step in the current buffer, and
+// returns that buffer's undo log to hand to endUndoStep, in case
+// the command switches buffers. Steps nest, so a command that calls
+// other commands still ends up as one undo step.
+func (e *Editor) beginUndoStep() *undoLog {
+	u := &e.undoLog
+	u.depth++
+	if u.depth == 1 {
+		u.current = &undoStep{cx: e.cx, cy: e.cy}
+	}
+	return u
+}
+
+// endUndoStep closes the outermost open undo step in undo log u,
+// pushing it on the undo stack if it changed anything. Consecutive
+// typing gets merged into one step.
+func (e *Editor) endUndoStep(u *undoLog) {
+	if u.depth == 0 {
+		return
+	}
+	u.depth--
+	if u.depth > 0 {
+		return
+	}
+	step := u.current
+	u.current = nil
+	top := u.top()
+	if len(step.edits) == 0 {
+		// Cursor motion and such ends a run of typing.
+		if top != nil {
+			top.typing = false
+		}
+		return
+	}
+	step.ecx, step.ecy = step.cx, step.cy
+	if u == &e.undoLog {
+		step.ecx, step.ecy = e.cx, e.cy
+	}
+	u.undone = nil
+	if step.typing && top != nil && top.typing && top != u.savedAt &&
+		top.ecx == step.cx && top.ecy == step.cy {
+		u.current = top
+		for _, ed := range step.edits {
+			u.record(ed)
+		}
+		u.current = nil
+		top.ecx, top.ecy = step.ecx, step.ecy
+		return
+	}
+	u.done = append(u.done, step)
+}
+
+func (e *Editor) recordEdit(kind editKind, at int, before, after []rune) {
+	if e.undoLog.current == nil || e.undoLog.replaying {
+		return
+	}
+	e.undoLog.record(edit{kind: kind, at: at, before: copyRunes(before), after: copyRunes(after)})
+}
+
+func (e *Editor) setRowChars(at int, s []rune) {
+	e.rows[at].Chars = copyRunes(s)
+	e.rows[at].Size = len(s)
+	e.rows[at].UpdateRow()
+	e.changes++
+	e.updateSyntax(at)
+}
+
+func (e *Editor) applyEdit(ed edit, reverse bool) {
+	switch {
+	case ed.kind == editChangeRow && reverse:
+		e.setRowChars(ed.at, ed.before)
+	case ed.kind == editChangeRow:
+		e.setRowChars(ed.at, ed.after)
+	case ed.kind == editInsertRow && !reverse:
+		e.insertRow(ed.at, copyRunes(ed.after))
+	case ed.kind == editDeleteRow && reverse:
+		e.insertRow(ed.at, copyRunes(ed.before))
+	default:
+		e.delRow(ed.at)
+	}
+}
+
+func (e *Editor) undo() {
+	u := &e.undoLog
+	step := u.top()
+	if step == nil {
+		e.SetStatusMessage("Nothing to undo")
+		return
+	}
+	u.done = u.done[:len(u.done)-1]
+	u.replaying = true
+	for i := len(step.edits) - 1; i >= 0; i-- {
+		e.applyEdit(step.edits[i], true)
+	}
+	u.replaying = false
+	step.typing = false
+	u.undone = append(u.undone, step)
+	e.placeCursor(step.cx, step.cy)
+	e.Dirty = u.top() != u.savedAt
+}
+
+func (e *Editor) redo() {
+	u := &e.undoLog
+	if len(u.undone) == 0 {
+		e.SetStatusMessage("Nothing to redo")
+		return
+	}
+	step := u.undone[len(u.undone)-1]
+	u.undone = u.undone[:len(u.undone)-1]
+	u.replaying = true
+	for _, ed := range step.edits {
+		e.applyEdit(ed, false)
+	}
+	u.replaying = false
+	u.done = append(u.done, step)
+	e.placeCursor(step.ecx, step.ecy)
+	e.Dirty = u.top() != u.savedAt
+}
+
+// markSaved notes that the file on disk matches the top of the
+// undo stack, so undoing back to here makes the file clean.
+func (e *Editor) markSaved() {
+	e.undoLog.savedAt = e.undoLog.top()
+	for _, r := range e.rows {
+		r.Modified = false
+	}
+}
+
+// placeCursor puts the cursor at cx, cy, keeping it inside the file.
+func (e *Editor) placeCursor(cx, cy int) {
+	if cy > e.numRows {
+		cy = e.numRows
+	}
+	if cy < 0 {
+		cy = 0
+	}
+	rowlen := 0
+	if cy < e.numRows {
+		rowlen = e.rows[cy].Size
+	}
+	if cx > rowlen {
+		cx = rowlen
+	}
+	if cx < 0 {
+		cx = 0
+	}
+	e.cx, e.cy = cx, cy
+}
@@ -0,0 +1,671 @@
+package editor
+
+import (
+	"unicode"
+
+	"GoKilo/keyboard"
+)
+
+/*** vi ***/
+
+/* With the vi profile on, the editor starts in normal mode, where
+ * keys are commands, like "3dw" to delete three words. A count,
+ * an operator (d, c or y) and a motion make up a command. Keys
+ * vi doesn't use, like Ctrl-S, still do what the keymap says. In
+ * insert mode keys work as they do without vi, and ESC goes back
+ * to normal mode.
+ */
+
+type viMode int
+
+const (
+	viNormal viMode = iota
+	viInsert
+	viVisual
+)
+
+var viModeNames = [...]string{
+	viNormal: "NORMAL",
+	viInsert: "INSERT",
+	viVisual: "VISUAL",
+}
+
+// viState holds what the editor has to remember between keys
+// in vi mode.
+type viState struct {
+	mode      viMode
+	pending   []int // keys of a command that isn't complete yet
+	change    []int // keys of the change being made, for '.'
+	recording bool  // the change goes on through insert mode
+	last      []int // keys of the last complete change
+	replaying bool
+}
+
+// viCommand is a normal mode command, made from keys.
+type viCommand struct {
+	count    int // 0 if none was typed
+	op       int // 'd', 'c', 'y', or 0 if there's no operator
+	key      int // the command, or the motion for op
+	arg      int // the key after the motion, for 'g'
+	hasCount bool
+}
+
+// viCommandMode reports whether keys are vi commands.
+func (e *Editor) viCommandMode() bool {
+	return e.vi != nil && e.vi.mode != viInsert
+}
+
+// record adds key c to the change being recorded for '.'.
+func (v *viState) record(c int) {
+	if v.recording && !v.replaying {
+		v.change = append(v.change, c)
+	}
+}
+
+// parseVi makes a command out of keys. It returns false for
+// done if more keys have to come, and false for ok if the keys
+// aren't a command.
+func parseVi(keys []int, visual bool) (cmd viCommand, done, ok bool) {
+	i := 0
+	count := func() int {
+		n := 0
+		for i < len(keys) && keys[i] >= '0' && keys[i] <= '9' && (n > 0 || keys[i] != '0') {
+			n = n*10 + keys[i] - '0'
+			i++
+		}
+		return n
+	}
+	next := func() (int, bool) {
+		if i == len(keys) {
+			return 0, false
+		}
+		i++
+		return keys[i-1], true
+	}
+
+	cmd.count = count()
+	k, more := next()
+	if !more {
+		return cmd, false, true
+	}
+	if !visual && (k == 'd' || k == 'c' || k == 'y') {
+		cmd.op = k
+		if n := count(); n > 0 {
+			cmd.count = maxInt(cmd.count, 1) * n
+		}
+		if k, more = next(); !more {
+			return cmd, false, true
+		}
+	}
+	cmd.key = k
+	cmd.hasCount = cmd.count > 0
+	if k == 'g' {
+		if cmd.arg, more = next(); !more {
+			return cmd, false, true
+		}
+		return cmd, true, cmd.arg == 'g'
+	}
+	return cmd, true, true
+}
+
+// viKey handles key c in normal or visual mode.
+func (e *Editor) viKey(c int) (bool, error) {
+	v := e.vi
+	if len(v.pending) == 0 && !viUses(c) {
+		cmd, _, err := e.commandFor(c)
+		if err != nil || cmd == nil {
+			return true, err
+		}
+		return e.runCommand(cmd)
+	}
+	if c == keyboard.ESCAPE {
+		v.pending = nil
+		if v.mode == viVisual {
+			e.mark = mark{}
+		}
+		return true, nil
+	}
+	v.pending = append(v.pending, c)
+	cmd, done, ok := parseVi(v.pending, v.mode == viVisual)
+	if !done && ok {
+		return true, nil
+	}
+	keys := v.pending
+	v.pending = nil
+	if !ok {
+		return true, nil
+	}
+	again, err := e.viRun(cmd, keys)
+	if v.mode != viInsert {
+		e.viClamp()
+	}
+	return again, err
+}
+
+// viUses reports whether c is a key with a meaning in normal mode.
+func viUses(c int) bool {
+	switch c {
+	case '\r', keyboard.BACKSPACE, keyboard.CTRL_H, keyboard.ESCAPE, keyboard.CTRL_R:
+		return true
+	}
+	return c >= ' ' && c <= '~'
+}
+
+// viChanges are the commands that change the text, for '.'.
+const viChanges = "dcxXDCsSpPJoOiaIA"
+
+// viRun runs cmd, which was typed as keys.
+func (e *Editor) viRun(cmd viCommand, keys []int) (bool, error) {
+	v := e.vi
+	n := maxInt(cmd.count, 1)
+	if v.mode == viNormal && !v.replaying && (cmd.op != 0 || containsKey(viChanges, cmd.key)) {
+		v.change = append([]int(nil), keys...)
+		v.recording = true
+		defer func() {
+			if v.mode != viInsert {
+				v.last, v.recording = v.change, false
+			}
+		}()
+	}
+	if cmd.op != 0 {
+		e.viOperate(cmd)
+		return true, nil
+	}
+	if x, y, _, _, ok := e.viMotion(cmd); ok {
+		e.cx, e.cy = x, y
+		return true, nil
+	}
+	if v.mode == viVisual {
+		return e.viVisualKey(cmd.key)
+	}
+
+	switch cmd.key {
+	case 'i':
+		v.mode = viInsert
+	case 'a':
+		if e.cy < e.numRows && e.cx < e.rows[e.cy].Size {
+			e.cx = e.rows[e.cy].NextGrapheme(e.cx)
+		}
+		v.mode = viInsert
+	case 'I':
+		e.cx = e.firstNonBlank(e.cy)
+		v.mode = viInsert
+	case 'A':
+		e.lineEnd()
+		v.mode = viInsert
+	case 'o':
+		e.lineEnd()
+		e.insertNewLine()
+		v.mode = viInsert
+	case 'O':
+		indent := copyRunes(leadingSpace(e.rowChars(e.cy)))
+		e.insertRow(e.cy, indent)
+		e.cx = len(indent)
+		v.mode = viInsert
+	case 'x', 'X':
+		e.viDeleteChars(n, cmd.key == 'X')
+	case 's':
+		e.viOperate(viCommand{count: n, op: 'c', key: 'l'})
+	case 'S':
+		e.viOperate(viCommand{count: n, op: 'c', key: 'c'})
+	case 'D':
+		e.viOperate(viCommand{count: n, op: 'd', key: '$'})
+	case 'C':
+		e.viOperate(viCommand{count: n, op: 'c', key: '$'})
+	case 'Y':
+		e.viOperate(viCommand{count: n, op: 'y', key: 'y'})
+	case 'p', 'P':
+		e.viPut(n, cmd.key == 'P')
+	case 'J':
+		for ; n > 0; n-- {
+			e.viJoin()
+		}
+	case 'u':
+		for ; n > 0; n-- {
+			e.undo()
+		}
+	case keyboard.CTRL_R:
+		for ; n > 0; n-- {
+			e.redo()
+		}
+	case '.':
+		e.viRepeat()
+	case 'v':
+		e.mark = mark{set: true, cx: e.cx, cy: e.cy}
+		v.mode = viVisual
+	case ':':
+		return e.exPrompt()
+	case '/':
+		find(e)
+	}
+	return true, nil
+}
+
+// viVisualKey handles the commands that work on the selection
+// in visual mode.
+func (e *Editor) viVisualKey(key int) (bool, error) {
+	sx, sy, ex, ey, _ := e.selection()
+	if ey < e.numRows && ex < e.rows[ey].Size {
+		// The character at the cursor is in the selection too.
+		ex = e.rows[ey].NextGrapheme(ex)
+	}
+	switch key {
+	case 'v':
+		e.mark = mark{}
+	case 'y':
+		e.kill(e.textBetween(sx, sy, ex, ey), false)
+		e.cx, e.cy = sx, sy
+		e.mark = mark{}
+	case 'd', 'x':
+		e.kill(e.textBetween(sx, sy, ex, ey), false)
+		e.deleteBetween(sx, sy, ex, ey)
+		e.mark = mark{}
+	case 'c', 's':
+		e.kill(e.textBetween(sx, sy, ex, ey), false)
+		e.deleteBetween(sx, sy, ex, ey)
+		e.mark = mark{}
+		e.vi.mode = viInsert
+	}
+	return true, nil
+}
+
+// viLeaveInsert goes back to normal mode from insert mode.
+func (e *Editor) viLeaveInsert() {
+	v := e.vi
+	v.mode = viNormal
+	if v.recording && !v.replaying {
+		v.last, v.recording = v.change, false
+	}
+	if e.cx > 0 {
+		e.cx = e.rows[e.cy].PrevGrapheme(e.cx)
+	}
+	e.viClamp()
+}
+
+// viRepeat does the last change again.
+func (e *Editor) viRepeat() {
+	v := e.vi
+	v.replaying = true
+	for _, c := range v.last {
+		if v.mode == viInsert {
+			e.runKey(e.keys.commands[c], c)
+		} else {
+			e.viKey(c)
+		}
+	}
+	if v.mode == viInsert {
+		e.viLeaveInsert()
+	}
+	v.replaying = false
+}
+
+// viClamp keeps the cursor on a character, the way vi does
+// outside of insert mode.
+func (e *Editor) viClamp() {
+	if e.numRows > 0 && e.cy >= e.numRows {
+		e.cy = e.numRows - 1
+	}
+	if e.cy < e.numRows {
+		if r := e.rows[e.cy]; r.Size > 0 && e.cx >= r.Size {
+			e.cx = r.PrevGrapheme(r.Size)
+		}
+	}
+}
+
+// rowChars returns the text of line y, which may be past the end.
+func (e *Editor) rowChars(y int) []rune {
+	if y < e.numRows {
+		return e.rows[y].Chars
+	}
+	return nil
+}
+
+// firstNonBlank returns the position of the first character
+// on line y that isn't a space or tab.
+func (e *Editor) firstNonBlank(y int) int {
+	return len(leadingSpace(e.rowChars(y)))
+}
+
+/*** vi motions ***/
+
+// viMotion returns where motion cmd.key goes to from the cursor,
+// whether it takes in whole lines, and whether the character it
+// lands on goes with the text before it for an operator. It
+// returns false for ok if cmd.key isn't a motion.
+func (e *Editor) viMotion(cmd viCommand) (x, y int, linewise, inclusive, ok bool) {
+	n := maxInt(cmd.count, 1)
+	x, y = e.cx, e.cy
+	last := maxInt(e.numRows-1, 0)
+	switch cmd.key {
+	case 'h', keyboard.BACKSPACE, keyboard.CTRL_H:
+		for ; n > 0 && x > 0; n-- {
+			x = e.rows[y].PrevGrapheme(x)
+		}
+	case 'l', ' ':
+		for ; n > 0 && x < len(e.rowChars(y)); n-- {
+			x = e.rows[y].NextGrapheme(x)
+		}
+	case 'j', 'k':
+		if cmd.key == 'k' {
+			n = -n
+		}
+		y = clamp(y+n, 0, last)
+		x = e.cxForRx(y, e.rx)
+		linewise = true
+	case '\r', '+', '-':
+		if cmd.key == '-' {
+			n = -n
+		}
+		y = clamp(y+n, 0, last)
+		x = e.firstNonBlank(y)
+		linewise = true
+	case 'w', 'W':
+		for ; n > 0; n-- {
+			x, y = e.viWordForward(x, y)
+		}
+	case 'b', 'B':
+		for ; n > 0; n-- {
+			x, y = e.viWordBackward(x, y)
+		}
+	case 'e', 'E':
+		for ; n > 0; n-- {
+			x, y = e.viWordEnd(x, y)
+		}
+		inclusive = true
+	case '0':
+		x = 0
+	case '^':
+		x = e.firstNonBlank(y)
+	case '$':
+		y = clamp(y+n-1, 0, last)
+		x = len(e.rowChars(y))
+	case 'G', 'g':
+		y = last
+		if cmd.hasCount || cmd.key == 'g' {
+			y = clamp(cmd.count-1, 0, last)
+		}
+		x = e.firstNonBlank(y)
+		linewise = true
+	default:
+		return 0, 0, false, false, false
+	}
+	return x, y, linewise, inclusive, true
+}
+
+// viClass sorts characters into spaces (0), word characters
+// (1) and punctuation (2), for moving by words.
+func viClass(c rune) int {
+	switch {
+	case c == ' ' || c == '\t':
+		return 0
+	case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.IsMark(c):
+		return 1
+	}
+	return 2
+}
+
+// viWordForward returns where the next word after (x, y) starts.
+// An empty line counts as a word.
+func (e *Editor) viWordForward(x, y int) (int, int) {
+	chars := e.rowChars(y)
+	if x < len(chars) {
+		if c := viClass(chars[x]); c != 0 {
+			for x < len(chars) && viClass(chars[x]) == c {
+				x++
+			}
+		}
+	}
+	for {
+		for x < len(chars) && viClass(chars[x]) == 0 {
+			x++
+		}
+		if x < len(chars) || y+1 >= e.numRows {
+			return x, y
+		}
+		x, y = 0, y+1
+		if chars = e.rows[y].Chars; len(chars) == 0 {
+			return x, y
+		}
+	}
+}
+
+// viWordBackward returns where the word before (x, y) starts.
+func (e *Editor) viWordBackward(x, y int) (int, int) {
+	chars := e.rowChars(y)
+	for {
+		for x > 0 && viClass(chars[x-1]) == 0 {
+			x--
+		}
+		if x > 0 || y == 0 {
+			break
+		}
+		y--
+		chars = e.rows[y].Chars
+		if x = len(chars); x == 0 {
+			return x, y
+		}
+	}
+	if x > 0 {
+		c := viClass(chars[x-1])
+		for x > 0 && viClass(chars[x-1]) == c {
+			x--
+		}
+	}
+	return x, y
+}
+
+// viWordEnd returns where the word after (x, y) ends, which
+// is the position of its last character.
+func (e *Editor) viWordEnd(x, y int) (int, int) {
+	chars := e.rowChars(y)
+	x++
+	for {
+		for x < len(chars) && viClass(chars[x]) == 0 {
+			x++
+		}
+		if x < len(chars) || y+1 >= e.numRows {
+			break
+		}
+		x, y = 0, y+1
+		chars = e.rows[y].Chars
+	}
+	if x >= len(chars) {
+		return maxInt(len(chars)-1, 0), y
+	}
+	c := viClass(chars[x])
+	for x+1 < len(chars) && viClass(chars[x+1]) == c {
+		x++
+	}
+	return x, y
+}
+
+/*** vi operators ***/
+
+// viOperate applies operator cmd.op to the text between the
+// cursor and where motion cmd.key goes, or to whole lines, for
+// an operator typed twice, like "dd".
+func (e *Editor) viOperate(cmd viCommand) {
+	if e.numRows == 0 {
+		if cmd.op == 'c' {
+			e.vi.mode = viInsert
+		}
+		return
+	}
+	n := maxInt(cmd.count, 1)
+	sx, sy := e.cx, e.cy
+	var ex, ey int
+	var linewise, inclusive, ok bool
+	switch {
+	case cmd.key == cmd.op:
+		ex, ey = 0, clamp(sy+n-1, 0, e.numRows-1)
+		linewise, ok = true, true
+	case cmd.op == 'c' && (cmd.key == 'w' || cmd.key == 'W') &&
+		sx < len(e.rowChars(sy)) && viClass(e.rows[sy].Chars[sx]) != 0:
+		// cw changes to the end of the word, not up to the next one.
+		cmd.key = 'e'
+		ex, ey, linewise, inclusive, ok = e.viMotion(cmd)
+	default:
+		ex, ey, linewise, inclusive, ok = e.viMotion(cmd)
+	}
+	if !ok {
+		return
+	}
+	if (cmd.key == 'w' || cmd.key == 'W') && ey > sy && ex == e.firstNonBlank(ey) {
+		// A word motion that goes onto another line stops
+		// at the end of the line it started on.
+		ey--
+		ex = len(e.rows[ey].Chars)
+	}
+	if ey < sy || ey == sy && ex < sx {
+		sx, sy, ex, ey = ex, ey, sx, sy
+	}
+	if ey >= e.numRows {
+		ey = e.numRows - 1
+		ex = len(e.rows[ey].Chars)
+	}
+	if linewise {
+		e.viLines(cmd.op, sy, ey)
+		return
+	}
+	if inclusive && ex < len(e.rows[ey].Chars) {
+		ex = e.rows[ey].NextGrapheme(ex)
+	}
+	e.kill(e.textBetween(sx, sy, ex, ey), false)
+	switch cmd.op {
+	case 'y':
+		e.cx, e.cy = sx, sy
+	case 'd':
+		e.deleteBetween(sx, sy, ex, ey)
+	case 'c':
+		e.deleteBetween(sx, sy, ex, ey)
+		e.vi.mode = viInsert
+	}
+}
+
+// viLines applies operator op to lines sy through ey.
+func (e *Editor) viLines(op int, sy, ey int) {
+	e.kill(append(e.textBetween(0, sy, len(e.rows[ey].Chars), ey), nil), false)
+	switch op {
+	case 'y':
+		e.cy = sy
+		e.SetStatusMessage("%d lines yanked", ey-sy+1)
+	case 'd':
+		switch {
+		case ey+1 < e.numRows:
+			e.deleteBetween(0, sy, 0, ey+1)
+		case sy > 0:
+			e.deleteBetween(len(e.rows[sy-1].Chars), sy-1, len(e.rows[ey].Chars), ey)
+			e.cy = sy - 1
+		default:
+			e.deleteBetween(0, 0, len(e.rows[ey].Chars), ey)
+		}
+		e.cx = e.firstNonBlank(e.cy)
+	case 'c':
+		indent := e.firstNonBlank(sy)
+		e.deleteBetween(indent, sy, len(e.rows[ey].Chars), ey)
+		e.vi.mode = viInsert
+	}
+}
+
+// viDeleteChars deletes n characters after the cursor, or
+// before it if back is true, without leaving the line.
+func (e *Editor) viDeleteChars(n int, back bool) {
+	if e.cy >= e.numRows {
+		return
+	}
+	r := e.rows[e.cy]
+	from, to := e.cx, e.cx
+	for ; n > 0; n-- {
+		if back && from > 0 {
+			from = r.PrevGrapheme(from)
+		} else if !back && to < r.Size {
+			to = r.NextGrapheme(to)
+		}
+	}
+	if from == to {
+		return
+	}
+	e.kill(e.textBetween(from, e.cy, to, e.cy), false)
+	for e.cx = to; e.cx > from; {
+		e.delChar()
+	}
+}
+
+// viPut puts what's on the clipboard after the cursor, or before
+// it if before is true, n times. Text that ends with a line end
+// goes in as whole lines, below the cursor line or above it.
+func (e *Editor) viPut(n int, before bool) {
+	clip := e.clipboard
+	if len(clip) == 0 {
+		e.SetStatusMessage("Clipboard is empty")
+		return
+	}
+	linewise := len(clip) > 1 && len(clip[len(clip)-1]) == 0
+	var text [][]rune
+	for ; n > 0; n-- {
+		if len(text) > 0 {
+			last := len(text) - 1
+			text[last] = append(copyRunes(text[last]), clip[0]...)
+			text = append(text, clip[1:]...)
+		} else {
+			text = append(text, clip...)
+		}
+	}
+	if !linewise {
+		if !before && e.cy < e.numRows && e.cx < e.rows[e.cy].Size {
+			e.cx = e.rows[e.cy].NextGrapheme(e.cx)
+		}
+		e.insertText(text)
+		if e.cx > 0 {
+			e.cx = e.rows[e.cy].PrevGrapheme(e.cx)
+		}
+		return
+	}
+	y := e.cy
+	switch {
+	case before:
+		e.cx = 0
+		e.insertText(text)
+	case y+1 < e.numRows:
+		y++
+		e.cx, e.cy = 0, y
+		e.insertText(text)
+	default:
+		// After the last line, so put in a line end first.
+		y = e.numRows
+		e.lineEnd()
+		e.insertText(append([][]rune{nil}, text[:len(text)-1]...))
+	}
+	e.cx, e.cy = e.firstNonBlank(y), y
+}
+
+// viJoin joins the next line onto the cursor line, with a space
+// between them in place of the next line's indentation.
+func (e *Editor) viJoin() {
+	if e.cy+1 >= e.numRows {
+		return
+	}
+	e.cx = e.rows[e.cy].Size
+	next := e.firstNonBlank(e.cy + 1)
+	e.deleteBetween(e.cx, e.cy, next, e.cy+1)
+	if e.cx > 0 && e.cx < e.rows[e.cy].Size && e.rows[e.cy].Chars[e.cx] != ')' {
+		e.insertChar(' ')
+		e.cx--
+	}
+}
+
+func containsKey(keys string, c int) bool {
+	for _, k := range keys {
+		if int(k) == c {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	"GoKilo/config"
+)
+
+// newViEditor returns an editor with the vi profile on, in
+// normal mode, editing lines.
+func newViEditor(t *testing.T, lines ...string) *Editor {
+	t.Helper()
+	e := newEditor(24, 80)
+	s := config.Default()
+	s.Profile = "vi"
+	if err := e.Configure(s); err != nil {
+		t.Fatal(err)
+	}
+	e.NewBuffer("")
+	for _, line := range lines {
+		e.AppendRow([]byte(line))
+	}
+	return e
+}
+
+// viKeys types keys in normal mode, each an undo step of its own,
+// as ProcessKeypress makes them.
+func viKeys(t *testing.T, e *Editor, keys string) {
+	t.Helper()
+	for _, c := range keys {
+		u := e.beginUndoStep()
+		_, err := e.viKey(int(c))
+		e.endUndoStep(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestViNormalMode(t *testing.T) {
+	text := []string{"one two three", "four five", "six"}
+	for _, tt := range []struct {
+		keys   string
+		want   []string
+		cx, cy int
+		clip   string
+	}{
+		{"w", text, 4, 0, ""},
+		{"2w", text, 8, 0, ""},
+		{"e", text, 2, 0, ""},
+		{"$", text, 12, 0, ""},
+		{"j$", text, 8, 1, ""},
+		{"G", text, 0, 2, ""},
+		{"Ggg", text, 0, 0, ""},
+		{"x", []string{"ne two three", "four five", "six"}, 0, 0, "o"},
+		{"3x", []string{" two three", "four five", "six"}, 0, 0, "one"},
+		{"$X", []string{"one two thre", "four five", "six"}, 11, 0, "e"},
+		{"dw", []string{"two three", "four five", "six"}, 0, 0, "one "},
+		{"d2w", []string{"three", "four five", "six"}, 0, 0, "one two "},
+		{"wD", []string{"one ", "four five", "six"}, 3, 0, "two three"},
+		{"de", []string{" two three", "four five", "six"}, 0, 0, "one"},
+		{"d$", []string{"", "four five", "six"}, 0, 0, "one two three"},
+		{"dd", []string{"four five", "six"}, 0, 0, "one two three\n"},
+		{"2dd", []string{"six"}, 0, 0, "one two three\nfour five\n"},
+		{"jdk", []string{"six"}, 0, 0, "one two three\nfour five\n"},
+		{"yyjp", []string{"one two three", "four five", "one two three", "six"}, 0, 2, "one two three\n"},
+		{"ywP", []string{"one one two three", "four five", "six"}, 3, 0, "one "},
+		{"J", []string{"one two three four five", "six"}, 13, 0, ""},
+		{"ddu", text, 0, 0, "one two three\n"},
+		{"x.", []string{"e two three", "four five", "six"}, 0, 0, "n"},
+		{"vly", text, 0, 0, "on"},
+		{"wvld", []string{"one o three", "four five", "six"}, 4, 0, "tw"},
+		{"l\bx", []string{"ne two three", "four five", "six"}, 0, 0, "o"},
+	} {
+		e := newViEditor(t, text...)
+		viKeys(t, e, tt.keys)
+		if got := e.bufferLines(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: lines = %q, want %q", tt.keys, got, tt.want)
+		}
+		if e.cx != tt.cx || e.cy != tt.cy {
+			t.Errorf("%q: cursor at %d, %d, want %d, %d", tt.keys, e.cx, e.cy, tt.cx, tt.cy)
+		}
+		if got := joinLines(e.clipboard); got != tt.clip {
+			t.Errorf("%q: clipboard holds %q, want %q", tt.keys, got, tt.clip)
+		}
+	}
+}
+
+// fakeClipboard is a system clipboard that just keeps the text.
+type fakeClipboard struct{ text string }
+
+func (c *fakeClipboard) Copy(text string) error { c.text = text; return nil }
+func (c *fakeClipboard) Paste() (string, error) { return c.text, nil }
+
+func TestViYanksReachKillRingAndSystemClipboard(t *testing.T) {
+	e := newViEditor(t, "one two three", "four")
+	system := &fakeClipboard{}
+	e.system = system
+	for _, tt := range []struct {
+		keys string
+		want string
+	}{
+		{"yw", "one "},
+		{"yy", "one two three\n"},
+		{"x", "o"},
+		{"jdd", "four\n"},
+		{"vly", "ne"},
+	} {
+		viKeys(t, e, tt.keys)
+		if system.text != tt.want {
+			t.Errorf("after %q, system clipboard holds %q, want %q", tt.keys, system.text, tt.want)
+		}
+		if got := joinLines(e.killRing.kills[0]); got != tt.want {
+			t.Errorf("after %q, newest kill is %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+	if n := len(e.killRing.kills); n != 5 {
+		t.Errorf("kill ring holds %d kills, want 5", n)
+	}
+}
+
+func joinLines(text [][]rune) string {
+	s := ""
+	for i, line := range text {
+		if i > 0 {
+			s += "\n"
+		}
+		s += string(line)
+	}
+	return s
+}
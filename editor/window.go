@@ -0,0 +1,282 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+
+	"GoKilo/keyboard"
+)
+
+/*** windows ***/
+
+// view is where the cursor is in a buffer, and which
+// part of the buffer shows on screen.
+type view struct {
+	cx     int
+	cy     int
+	rx     int
+	rowoff int
+	coloff int
+	mark   mark
+}
+
+// window instances show a buffer in a rectangle of the screen,
+// with a status line underneath. Several windows can show the
+// same buffer, each with its own view of it.
+type window struct {
+	*buffer
+	view
+	top        int
+	left       int
+	screenRows int
+	screenCols int
+}
+
+// pane instances make up a tree that divides the screen among
+// the windows. A leaf pane holds a window; any other pane splits
+// its rectangle between panes a and b.
+type pane struct {
+	win      *window
+	vertical bool    // a and b side by side, rather than a above b
+	ratio    float64 // a's share of the rectangle
+	a, b     *pane
+	parent   *pane
+	top      int
+	left     int
+	rows     int
+	cols     int
+}
+
+func clamp(n, lo, hi int) int {
+	if n > hi {
+		n = hi
+	}
+	if n < lo {
+		n = lo
+	}
+	return n
+}
+
+// place gives the pane the rectangle at top, left, and shares
+// it out among the panes below. A window's status line takes
+// up the last row of its rectangle, and side by side panes
+// have a column between them for a separator.
+func (p *pane) place(top, left, rows, cols int) {
+	p.top, p.left, p.rows, p.cols = top, left, rows, cols
+	switch {
+	case p.win != nil:
+		p.win.top, p.win.left = top, left
+		p.win.screenRows = clamp(rows-1, 1, rows)
+		p.win.screenCols = clamp(cols, 1, cols)
+	case p.vertical:
+		acols := clamp(int(p.ratio*float64(cols-1)+0.5), 1, cols-2)
+		p.a.place(top, left, rows, acols)
+		p.b.place(top, left+acols+1, rows, cols-acols-1)
+	default:
+		arows := clamp(int(p.ratio*float64(rows)+0.5), 2, rows-2)
+		p.a.place(top, left, arows, cols)
+		p.b.place(top+arows, left, rows-arows, cols)
+	}
+}
+
+// windows lists the windows in the pane, top to bottom and
+// left to right.
+func (p *pane) windows() []*window {
+	if p.win != nil {
+		return []*window{p.win}
+	}
+	return append(p.a.windows(), p.b.windows()...)
+}
+
+// find returns the leaf pane holding window w.
+func (p *pane) find(w *window) *pane {
+	if p.win != nil {
+		if p.win == w {
+			return p
+		}
+		return nil
+	}
+	if f := p.a.find(w); f != nil {
+		return f
+	}
+	return p.b.find(w)
+}
+
+// layout shares the screen, less the message bar, among the windows.
+func (e *Editor) layout() {
+	e.root.place(0, 0, e.termRows-1, e.termCols)
+}
+
+// splitWindow divides the current window in two, both showing
+// the current buffer. The cursor stays in the top or left half.
+func (e *Editor) splitWindow(vertical bool) {
+	p := e.root.find(e.window)
+	if (vertical && p.cols < 5) || (!vertical && p.rows < 4) {
+		e.SetStatusMessage("No room to split this window")
+		return
+	}
+	nw := &window{buffer: e.buffer, view: e.view}
+	p.a = &pane{win: p.win, parent: p}
+	p.b = &pane{win: nw, parent: p}
+	p.win = nil
+	p.vertical = vertical
+	p.ratio = 0.5
+	e.layout()
+}
+
+// closeWindow gets rid of the current window, giving its
+// space to the window next to it.
+func (e *Editor) closeWindow() {
+	p := e.root.find(e.window)
+	if p.parent == nil {
+		e.SetStatusMessage("Can't close the last window")
+		return
+	}
+	parent := p.parent
+	sibling := parent.a
+	if sibling == p {
+		sibling = parent.b
+	}
+	parent.win = sibling.win
+	parent.vertical = sibling.vertical
+	parent.ratio = sibling.ratio
+	parent.a, parent.b = sibling.a, sibling.b
+	if parent.a != nil {
+		parent.a.parent = parent
+		parent.b.parent = parent
+	}
+	e.window = parent.windows()[0]
+	e.layout()
+}
+
+// closeOtherWindows leaves the current window as the only one.
+func (e *Editor) closeOtherWindows() {
+	e.root = &pane{win: e.window}
+	e.layout()
+}
+
+// cycleWindow moves the focus dir windows forward or backward.
+func (e *Editor) cycleWindow(dir int) {
+	wins := e.root.windows()
+	for i, w := range wins {
+		if w == e.window {
+			n := len(wins)
+			e.window = wins[((i+dir)%n+n)%n]
+			return
+		}
+	}
+}
+
+// focusWindow moves the focus to the window next to the current
+// one in the direction of arrow key key, preferring the one
+// alongside the cursor.
+func (e *Editor) focusWindow(key int) {
+	cur := e.window
+	row := cur.top + cur.cy - cur.rowoff
+	col := cur.left + e.gutterWidth() + cur.rx - cur.coloff
+	var found *window
+	for _, w := range e.root.windows() {
+		var adjacent, alongside bool
+		switch key {
+		case keyboard.ARROW_UP:
+			adjacent = w.top+w.screenRows+1 == cur.top
+		case keyboard.ARROW_DOWN:
+			adjacent = cur.top+cur.screenRows+1 == w.top
+		case keyboard.ARROW_LEFT:
+			adjacent = w.left+w.screenCols+1 == cur.left
+		case keyboard.ARROW_RIGHT:
+			adjacent = cur.left+cur.screenCols+1 == w.left
+		}
+		if key == keyboard.ARROW_UP || key == keyboard.ARROW_DOWN {
+			alongside = col >= w.left && col < w.left+w.screenCols
+		} else {
+			alongside = row >= w.top && row <= w.top+w.screenRows
+		}
+		if adjacent && (found == nil || alongside) {
+			found = w
+		}
+	}
+	if found != nil {
+		e.window = found
+	}
+}
+
+// resizeWindow makes the current window delta rows (or columns,
+// if vertical is true) bigger, taking the space from its neighbor.
+func (e *Editor) resizeWindow(vertical bool, delta int) {
+	child := e.root.find(e.window)
+	p := child.parent
+	for p != nil && p.vertical != vertical {
+		child, p = p, p.parent
+	}
+	if p == nil {
+		return
+	}
+	extent := p.rows
+	if vertical {
+		extent = p.cols - 1
+	}
+	step := float64(delta) / float64(extent)
+	if child == p.b {
+		step = -step
+	}
+	p.ratio += step
+	if p.ratio < 0.01 {
+		p.ratio = 0.01
+	} else if p.ratio > 0.99 {
+		p.ratio = 0.99
+	}
+	e.layout()
+}
+
+// windowCommand reads the key after the one for the window
+// command, and does what it says to the windows.
+func (e *Editor) windowCommand() {
+	e.SetStatusMessage("%s: s/v split | c close | o only | w/arrows move | +-<> resize", e.keyFor("window"))
+	e.RefreshScreen()
+	c, err := e.readKey()
+	if err != nil {
+		return
+	}
+	e.SetStatusMessage("")
+	switch c {
+	case 's', 'S':
+		e.splitWindow(false)
+	case 'v', 'V':
+		e.splitWindow(true)
+	case 'c', 'q':
+		e.closeWindow()
+	case 'o':
+		e.closeOtherWindows()
+	case 'w', keyboard.CTRL_W:
+		e.cycleWindow(1)
+	case 'W':
+		e.cycleWindow(-1)
+	case keyboard.ARROW_UP, keyboard.ARROW_DOWN,
+		keyboard.ARROW_LEFT, keyboard.ARROW_RIGHT:
+		e.focusWindow(c)
+	case '+':
+		e.resizeWindow(false, 1)
+	case '-':
+		e.resizeWindow(false, -1)
+	case '>':
+		e.resizeWindow(true, 1)
+	case '<':
+		e.resizeWindow(true, -1)
+	}
+}
+
+// drawSeparators puts a column of bars between side by side panes.
+func (e *Editor) drawSeparators(ab *bytes.Buffer, p *pane) {
+	if p.win != nil {
+		return
+	}
+	if p.vertical {
+		col := p.b.left
+		for y := p.top; y < p.top+p.rows; y++ {
+			ab.WriteString(fmt.Sprintf("\x1b[%d;%dH\x1b[7m|\x1b[m", y+1, col))
+		}
+	}
+	e.drawSeparators(ab, p.a)
+	e.drawSeparators(ab, p.b)
+}
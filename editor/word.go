@@ -0,0 +1,98 @@
+package editor
+
+import (
+	"GoKilo/highlighter"
+)
+
+/*** words ***/
+
+// wordForward returns the position just past the end of the next
+// word after (cx, cy), skipping over separators and line ends.
+func (e *Editor) wordForward(cx, cy int) (int, int) {
+	for cy < e.numRows {
+		r := e.rows[cy]
+		for cx < r.Size && highlighter.IsSeparator(r.Chars[cx]) {
+			cx++
+		}
+		if cx < r.Size || cy == e.numRows-1 {
+			break
+		}
+		cx, cy = 0, cy+1
+	}
+	if cy == e.numRows {
+		return cx, cy
+	}
+	r := e.rows[cy]
+	for cx < r.Size && !highlighter.IsSeparator(r.Chars[cx]) {
+		cx++
+	}
+	return cx, cy
+}
+
+// wordBackward returns the position of the start of the word
+// before (cx, cy), skipping back over separators and line ends.
+func (e *Editor) wordBackward(cx, cy int) (int, int) {
+	if cy == e.numRows {
+		if cy == 0 {
+			return 0, 0
+		}
+		cy--
+		cx = e.rows[cy].Size
+	}
+	for {
+		r := e.rows[cy]
+		for cx > 0 && highlighter.IsSeparator(r.Chars[cx-1]) {
+			cx--
+		}
+		if cx > 0 || cy == 0 {
+			break
+		}
+		cy--
+		cx = e.rows[cy].Size
+	}
+	r := e.rows[cy]
+	for cx > 0 && !highlighter.IsSeparator(r.Chars[cx-1]) {
+		cx--
+	}
+	return cx, cy
+}
+
+// moveWord moves the cursor to the end of the next word,
+// or if dir is negative, the start of the previous word.
+func (e *Editor) moveWord(dir int) {
+	if dir < 0 {
+		e.cx, e.cy = e.wordBackward(e.cx, e.cy)
+	} else {
+		e.cx, e.cy = e.wordForward(e.cx, e.cy)
+	}
+}
+
+// wordRange returns the text between the cursor and where
+// moveWord would go, in file order.
+func (e *Editor) wordRange(dir int) (sx, sy, ex, ey int) {
+	sx, sy, ex, ey = e.cx, e.cy, e.cx, e.cy
+	if dir < 0 {
+		sx, sy = e.wordBackward(e.cx, e.cy)
+	} else {
+		ex, ey = e.wordForward(e.cx, e.cy)
+	}
+	return sx, sy, ex, ey
+}
+
+// deleteWord deletes from the cursor to where moveWord would go.
+func (e *Editor) deleteWord(dir int) {
+	sx, sy, ex, ey := e.wordRange(dir)
+	if sx == ex && sy == ey {
+		return
+	}
+	e.deleteBetween(sx, sy, ex, ey)
+}
+
+// moveToEnd moves the cursor to the end of the last line.
+func (e *Editor) moveToEnd() {
+	if e.numRows == 0 {
+		return
+	}
+	e.cy = e.numRows - 1
+	e.cx = e.rows[e.cy].Size
+}
@@ -5,40 +5,103 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 )
 
-// Open chooses os.Args[1] as a filename to open.
-// Returns filename, nil or "", err, or "", nil,
-// which is ugly.
-func Open(filenames []string, appendF func([]byte)) (string, error) {
-	if len(filenames) == 1 {
-		return "", nil
-	}
-	filename := filenames[1]
+// Target is a file named on the command line, with the line
+// and column to start editing at. Line and Col are 0 when
+// the command line doesn't say.
+type Target struct {
+	Filename string
+	Line     int
+	Col      int
+}
+
+// ParseArgs finds the files to edit in command line arguments
+// args, which don't include the program name. A "+N" argument
+// sets the line for the file after it, and a file name can have
+// ":line" or ":line:col" on the end, the way compilers report
+// positions, unless a file exists with that whole name.
+func ParseArgs(args []string) []Target {
+	var targets []Target
+	line := 0
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "+") {
+			if n, err := strconv.Atoi(arg[1:]); err == nil {
+				line = n
+				continue
+			}
+		}
+		t := Target{Filename: arg, Line: line}
+		line = 0
+		if _, err := os.Stat(arg); err != nil {
+			t = splitPosition(t)
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// splitPosition takes ":line" or ":line:col" off the end of t's
+// file name, if it has them.
+func splitPosition(t Target) Target {
+	parts := strings.Split(t.Filename, ":")
+	var nums []int
+	for len(parts) > 1 && len(nums) < 2 {
+		n, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil || n < 1 {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		parts = parts[:len(parts)-1]
+	}
+	if len(nums) == 0 {
+		return t
+	}
+	t.Filename = strings.Join(parts, ":")
+	t.Line = nums[0]
+	if len(nums) == 2 {
+		t.Col = nums[1]
+	}
+	return t
+}
+
+// Open reads the file named filename, handing each line
+// of it to appendF without the line ending. It returns the
+// Format of the file, for writing it back out the same way.
+func Open(filename string, appendF func([]byte)) (Format, error) {
 	fd, er := os.Open(filename)
 	if er != nil {
-		return filename, er
+		return DefaultFormat(), er
 	}
 	defer fd.Close()
-	fp := bufio.NewReader(fd)
+	return readLines(bufio.NewReader(fd), appendF)
+}
 
-	var err error
-	for line, err := fp.ReadBytes('\n'); err == nil; line, err = fp.ReadBytes('\n') {
-		// Trim trailing newlines and carriage returns
-		for c := line[len(line)-1]; len(line) > 0 && (c == '\n' || c == '\r'); {
-			line = line[:len(line)-1]
-			if len(line) > 0 {
-				c = line[len(line)-1]
-			}
-		}
-		appendF(line)
+// readLines does the work of Open, on whatever fp reads from.
+func readLines(fp *bufio.Reader, appendF func([]byte)) (Format, error) {
+	var lines [][]byte
+	line, err := fp.ReadBytes('\n')
+	for ; err == nil; line, err = fp.ReadBytes('\n') {
+		lines = append(lines, line)
+	}
+	if err != io.EOF {
+		return DefaultFormat(), err
+	}
+	if len(line) > 0 {
+		// Last line, with no line ending
+		lines = append(lines, line)
 	}
 
-	if err != nil && err != io.EOF {
-		return "", err
+	format := decode(lines)
+	for _, line := range lines {
+		appendF(line)
 	}
 
-	return filename, nil
+	return format, nil
 }
 
 // Save puts all the bytes that func getBytes returns
@@ -46,23 +109,130 @@ func Open(filenames []string, appendF func([]byte)) (string, error) {
 // a message, and a boolean. The latter indicates whether
 // the editor should consider its internal file representation
 // as still dirty, or clean.
+//
+// The bytes go into a temporary file in the same directory,
+// which then gets renamed over the original, so a failure part
+// way through leaves the original alone. The new file gets the
+// original's permissions and ownership. If it can't have the
+// ownership, since only root can give a file away, the original
+// gets overwritten in place instead, which keeps its owner.
+// Saving to a symbolic link saves to the file it points to.
 func Save(filename string, getBytes func() (string, int)) (msg string, stillDirty bool) {
-	fp, e := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	target := resolveLink(filename)
+	mode := os.FileMode(0644)
+	info, statErr := os.Stat(target)
+	if statErr == nil {
+		mode = info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	}
+
+	dir, base := filepath.Split(target)
+	if dir == "" {
+		dir = "."
+	}
+	fp, e := os.CreateTemp(dir, "."+base+".kilo-*")
 	if e != nil {
-		return fmt.Sprintf("Can't save! file open error %s", e), true
+		if os.IsPermission(e) {
+			// Can't make files in the directory, but
+			// maybe the file itself is writable.
+			return saveInPlace(target, mode, getBytes)
+		}
+		return fmt.Sprintf("Can't save! temp file error %s", e), true
 	}
-	defer fp.Close()
+	tmpname := fp.Name()
+
 	buf, length := getBytes()
-	stillDirty = true
-	n, err := io.WriteString(fp, buf)
+	err := writeAll(fp, buf, length)
 	if err == nil {
-		if n == length {
-			stillDirty = false
-			msg = fmt.Sprintf("%d bytes written to disk", length)
-		} else {
-			msg = fmt.Sprintf("wanted to write %d bytes to file, wrote %d", length, n)
+		err = fp.Sync()
+	}
+	if err == nil {
+		err = fp.Chmod(mode)
+	}
+	chownErr := error(nil)
+	if err == nil && statErr == nil {
+		if st, ok := info.Sys().(*syscall.Stat_t); ok &&
+			(int(st.Uid) != os.Geteuid() || int(st.Gid) != os.Getegid()) {
+			chownErr = fp.Chown(int(st.Uid), int(st.Gid))
 		}
-		return msg, stillDirty
 	}
-	return fmt.Sprintf("Can't save! I/O error %s", err), stillDirty
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && chownErr != nil {
+		os.Remove(tmpname)
+		return saveInPlace(target, mode, getBytes)
+	}
+	if err == nil {
+		err = os.Rename(tmpname, target)
+	}
+	if err != nil {
+		os.Remove(tmpname)
+		return fmt.Sprintf("Can't save! I/O error %s", err), true
+	}
+	syncDir(dir)
+	return fmt.Sprintf("%d bytes written to disk", length), false
+}
+
+// resolveLink follows filename through any symbolic links to
+// the file they point to, even if that file doesn't exist yet.
+func resolveLink(filename string) string {
+	for i := 0; i < 40; i++ {
+		info, err := os.Lstat(filename)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return filename
+		}
+		dest, err := os.Readlink(filename)
+		if err != nil {
+			return filename
+		}
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(filepath.Dir(filename), dest)
+		}
+		filename = dest
+	}
+	return filename
+}
+
+func writeAll(fp *os.File, buf string, length int) error {
+	n, err := io.WriteString(fp, buf)
+	if err == nil && n != length {
+		err = fmt.Errorf("wanted to write %d bytes to file, wrote %d", length, n)
+	}
+	return err
+}
+
+// saveInPlace overwrites filename the old-fashioned way, for when
+// a temporary file next to it can't be made, or can't be given the
+// file's owner. That isn't safe: a failure part way through leaves
+// the file damaged, so the messages say it was saved in place. The
+// file only gets cut down to size after the new bytes are all in,
+// so a failure never leaves it empty.
+func saveInPlace(filename string, mode os.FileMode, getBytes func() (string, int)) (msg string, stillDirty bool) {
+	buf, length := getBytes()
+	fp, e := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, mode)
+	if e != nil {
+		return fmt.Sprintf("Can't save! file open error %s", e), true
+	}
+	err := writeAll(fp, buf, length)
+	if err == nil {
+		err = fp.Truncate(int64(length))
+	}
+	if err == nil {
+		err = fp.Sync()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Sprintf("Can't save! I/O error saving in place, file may be damaged: %s", err), true
+	}
+	return fmt.Sprintf("%d bytes written to disk in place", length), false
+}
+
+// syncDir makes sure a rename in directory dir is on disk.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
 }
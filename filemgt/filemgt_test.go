@@ -0,0 +1,62 @@
+package filemgt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func bytesFor(s string) func() (string, int) {
+	return func() (string, int) { return s, len(s) }
+}
+
+func TestSaveInPlace(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(name, []byte("a long first version\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"short\n", "a much longer second version\n", ""} {
+		msg, dirty := saveInPlace(name, 0644, bytesFor(want))
+		if dirty || !strings.Contains(msg, "in place") {
+			t.Errorf("saveInPlace(%q) = %q, %v", want, msg, dirty)
+		}
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("after saveInPlace(%q), file holds %q", want, got)
+		}
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("saving in place changed the mode to %v, %v", info.Mode(), err)
+	}
+}
+
+func TestSaveKeepsModeAndLinks(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "f.sh")
+	link := filepath.Join(dir, "link")
+	if err := os.WriteFile(name, []byte("old\n"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("f.sh", link); err != nil {
+		t.Fatal(err)
+	}
+	if msg, dirty := Save(link, bytesFor("new\n")); dirty {
+		t.Fatalf("Save: %s", msg)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "new\n" {
+		t.Errorf("file holds %q, want %q", got, "new\n")
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("saving through the link replaced it")
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("mode is %v, %v, want 0750", info.Mode(), err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, ".f.sh.kilo-*")); len(left) > 0 {
+		t.Errorf("temp files left behind: %q", left)
+	}
+}
@@ -0,0 +1,93 @@
+package filemgt
+
+import (
+	"bytes"
+)
+
+var bom = []byte{0xef, 0xbb, 0xbf}
+
+// Format describes how a file's lines are laid out on disk,
+// so that saving the file writes them back out the same way.
+// In a file with mixed line endings, lines keep any "\r" as
+// part of their text, and get written with "\n".
+type Format struct {
+	CRLF         bool // lines end in "\r\n" rather than "\n"
+	Mixed        bool // some lines end in "\r\n", others in "\n"
+	FinalNewline bool // the last line has a line ending
+	BOM          bool // the file starts with a UTF-8 byte order mark
+}
+
+// DefaultFormat is the Format for a new file.
+func DefaultFormat() Format {
+	return Format{FinalNewline: true}
+}
+
+func (f Format) String() string {
+	s := "LF"
+	switch {
+	case f.Mixed:
+		s = "mixed"
+	case f.CRLF:
+		s = "CRLF"
+	}
+	if f.BOM {
+		s += " BOM"
+	}
+	if !f.FinalNewline {
+		s += " noeol"
+	}
+	return s
+}
+
+// Encode lays out lines the way Format f says, line
+// endings and all.
+func (f Format) Encode(lines [][]byte) []byte {
+	var buf bytes.Buffer
+	eol := "\n"
+	if f.CRLF && !f.Mixed {
+		eol = "\r\n"
+	}
+	if f.BOM {
+		buf.Write(bom)
+	}
+	for i, line := range lines {
+		buf.Write(line)
+		if i < len(lines)-1 || f.FinalNewline {
+			buf.WriteString(eol)
+		}
+	}
+	return buf.Bytes()
+}
+
+// decode works out the Format of a file from its lines, as
+// read with their line endings, and takes the line endings
+// (and any byte order mark) off of the lines.
+func decode(lines [][]byte) Format {
+	f := Format{FinalNewline: len(lines) == 0}
+	if len(lines) > 0 && bytes.HasPrefix(lines[0], bom) {
+		f.BOM = true
+		lines[0] = lines[0][len(bom):]
+	}
+	crlf, lf := 0, 0
+	for _, line := range lines {
+		switch {
+		case bytes.HasSuffix(line, []byte("\r\n")):
+			crlf++
+		case bytes.HasSuffix(line, []byte("\n")):
+			lf++
+		}
+	}
+	f.CRLF = crlf > 0
+	f.Mixed = crlf > 0 && lf > 0
+	for i, line := range lines {
+		if bytes.HasSuffix(line, []byte("\n")) {
+			line = line[:len(line)-1]
+			if f.CRLF && !f.Mixed {
+				line = line[:len(line)-1]
+			}
+			f.FinalNewline = i == len(lines)-1
+		}
+		lines[i] = line
+	}
+	return f
+}
@@ -0,0 +1,64 @@
+package filemgt
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"time"
+)
+
+// Stamp identifies one version of a file on disk, so that
+// the editor can tell if something else changed the file.
+type Stamp struct {
+	Exists  bool
+	ModTime time.Time
+	Size    int64
+	Hash    [sha256.Size]byte
+}
+
+// StampOf reads the file named filename to make its Stamp.
+// A file that doesn't exist gets a Stamp that says so.
+func StampOf(filename string) (Stamp, error) {
+	var s Stamp
+	fd, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return s, err
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, fd); err != nil {
+		return s, err
+	}
+	s.Exists = true
+	s.ModTime = info.ModTime()
+	s.Size = info.Size()
+	copy(s.Hash[:], h.Sum(nil))
+	return s, nil
+}
+
+// Changed reports whether the file named filename is different
+// from the version that Stamp s came from. It only reads the file
+// if its size or modification time changed, and then only says
+// it changed if its contents did. It also returns the file's
+// current Stamp, which is s if the file didn't change.
+func Changed(filename string, s Stamp) (Stamp, bool) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return Stamp{}, s.Exists && os.IsNotExist(err)
+	}
+	if s.Exists && info.Size() == s.Size && info.ModTime().Equal(s.ModTime) {
+		return s, false
+	}
+	now, err := StampOf(filename)
+	if err != nil {
+		return s, false
+	}
+	return now, now.Exists != s.Exists || now.Hash != s.Hash
+}
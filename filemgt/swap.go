@@ -0,0 +1,141 @@
+package filemgt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The first line of every swap file.
+const swapMagic = "kilo swap file"
+
+// SwapInfo describes a swap file: which kilo wrote it, and
+// whether it holds changes that never got saved.
+type SwapInfo struct {
+	PID      int
+	Host     string
+	Modified bool
+	ModTime  time.Time
+}
+
+// SwapName returns the name of the swap file for file filename,
+// a hidden file next to the file itself.
+func SwapName(filename string) string {
+	dir, base := filepath.Split(resolveLink(filename))
+	return filepath.Join(dir, "."+base+".kilo.swp")
+}
+
+// Running reports whether the kilo that wrote the swap file is
+// still running. A kilo on some other host might be, so that
+// counts as running too.
+func (s SwapInfo) Running() bool {
+	if host, _ := os.Hostname(); host != s.Host {
+		return true
+	}
+	err := syscall.Kill(s.PID, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// Mine reports whether this process wrote the swap file.
+func (s SwapInfo) Mine() bool {
+	host, _ := os.Hostname()
+	return s.Host == host && s.PID == os.Getpid()
+}
+
+// WriteSwap replaces the swap file for file filename. When modified
+// is true, the swap file also gets the bytes that func getBytes returns,
+// the unsaved contents of the file. The new swap file goes in place
+// with a rename, so a crash while writing it leaves the old one.
+func WriteSwap(filename string, modified bool, getBytes func() (string, int)) error {
+	swapname := SwapName(filename)
+	dir, base := filepath.Split(swapname)
+	if dir == "" {
+		dir = "."
+	}
+	fp, err := os.CreateTemp(dir, base+"-*")
+	if err != nil {
+		return err
+	}
+	tmpname := fp.Name()
+
+	host, _ := os.Hostname()
+	header := fmt.Sprintf("%s\npid %d\nhost %s\nmodified %t\n\n", swapMagic, os.Getpid(), host, modified)
+	err = writeAll(fp, header, len(header))
+	if err == nil && modified {
+		buf, length := getBytes()
+		err = writeAll(fp, buf, length)
+	}
+	if err == nil {
+		err = fp.Sync()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpname, swapname)
+	}
+	if err != nil {
+		os.Remove(tmpname)
+	}
+	return err
+}
+
+// ReadSwap reads the swap file for file filename, handing each line
+// of the unsaved contents it holds, if any, to appendF, the way Open
+// does. It returns an error satisfying os.IsNotExist if there's no
+// swap file.
+func ReadSwap(filename string, appendF func([]byte)) (SwapInfo, Format, error) {
+	var info SwapInfo
+	fd, err := os.Open(SwapName(filename))
+	if err != nil {
+		return info, DefaultFormat(), err
+	}
+	defer fd.Close()
+	if st, err := fd.Stat(); err == nil {
+		info.ModTime = st.ModTime()
+	}
+	fp := bufio.NewReader(fd)
+
+	line, err := fp.ReadString('\n')
+	if err != nil || strings.TrimSuffix(line, "\n") != swapMagic {
+		return info, DefaultFormat(), errors.New("not a kilo swap file")
+	}
+	for {
+		line, err = fp.ReadString('\n')
+		if err != nil {
+			return info, DefaultFormat(), errors.New("swap file header cut short")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		name, value := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			name, value = line[:i], line[i+1:]
+		}
+		switch name {
+		case "pid":
+			info.PID, _ = strconv.Atoi(value)
+		case "host":
+			info.Host = value
+		case "modified":
+			info.Modified = value == "true"
+		}
+	}
+	if !info.Modified {
+		return info, DefaultFormat(), nil
+	}
+	format, err := readLines(fp, appendF)
+	return info, format, err
+}
+
+// RemoveSwap removes the swap file for file filename.
+func RemoveSwap(filename string) {
+	os.Remove(SwapName(filename))
+}
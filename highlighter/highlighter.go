@@ -1,17 +1,18 @@
 package highlighter
 
 import (
-	"bytes"
+	"fmt"
+	"strconv"
 	"strings"
 	"unicode"
 
 	"GoKilo/row"
 )
 
-// Highlight types. Each byte in Row.Render gets assigned
+// Highlight types. Each rune in Row.Render gets assigned
 // one of these values, stored in Row.Hl. Used to decide
 // what VT-100 escape sequence to print out when displaying
-// bytes to screen.
+// runes to screen.
 const (
 	HL_NORMAL    = 0
 	HL_COMMENT   = iota
@@ -24,15 +25,20 @@ const (
 )
 
 // Syntax instances hold substrings used to do simple
-// "syntax coloring" of a file under edit.
+// "syntax coloring" of a file under edit, and the rules
+// for indenting lines of it.
 type Syntax struct {
 	Filetype               string
 	filematch              []string
 	keywords               []string
-	singleLineCommentStart []byte
-	multiLineCommentStart  []byte
-	multiLineCommentEnd    []byte
+	singleLineCommentStart []rune
+	multiLineCommentStart  []rune
+	multiLineCommentEnd    []rune
 	flags                  int
+	indentAfter            string // a line ending in one of these indents the next
+	dedentOn               string // one of these typed first on a line dedents it
+	TabStop                int    // 0 to go with the user's settings
+	ExpandTab              bool   // indent with spaces, if TabStop isn't 0
 }
 
 var hldb = []*Syntax{
@@ -45,10 +51,12 @@ var hldb = []*Syntax{
 			"int|", "long|", "double|", "float|", "char|",
 			"unsigned|", "signed|", "void|",
 		},
-		singleLineCommentStart: []byte{'/', '/'},
-		multiLineCommentStart:  []byte{'/', '*'},
-		multiLineCommentEnd:    []byte{'*', '/'},
+		singleLineCommentStart: []rune{'/', '/'},
+		multiLineCommentStart:  []rune{'/', '*'},
+		multiLineCommentEnd:    []rune{'*', '/'},
 		flags:                  HL_HIGHLIGHT_NUMBERS | HL_HIGHLIGHT_STRINGS,
+		indentAfter:            "{([",
+		dedentOn:               "})]",
 	},
 	&Syntax{
 		Filetype:  "Go",
@@ -61,10 +69,13 @@ var hldb = []*Syntax{
 			"unsigned|", "signed|", "string|", "chan|", "bool|",
 			"rune|", "complex|", "error|", "int64|", "map|",
 		},
-		singleLineCommentStart: []byte{'/', '/'},
-		multiLineCommentStart:  []byte{'/', '*'},
-		multiLineCommentEnd:    []byte{'*', '/'},
+		singleLineCommentStart: []rune{'/', '/'},
+		multiLineCommentStart:  []rune{'/', '*'},
+		multiLineCommentEnd:    []rune{'*', '/'},
 		flags:                  HL_HIGHLIGHT_NUMBERS | HL_HIGHLIGHT_STRINGS,
+		indentAfter:            "{([",
+		dedentOn:               "})]",
+		TabStop:                8,
 	},
 }
 
@@ -73,14 +84,16 @@ const (
 	HL_HIGHLIGHT_STRINGS = 1 << iota
 )
 
-var separators = []byte(",.()+-/*=~%<>[]; \t\n\r")
+var separators = ",.()+-/*=~%<>[]; \t\n\r"
 
-func isSeparator(c byte) bool {
-	return bytes.IndexByte(separators, c) >= 0
+// IsSeparator reports whether c comes between words, for finding
+// keywords to highlight, and for the editor's moves by words.
+func IsSeparator(c rune) bool {
+	return strings.ContainsRune(separators, c)
 }
 
 // UpdateSyntax fills in the Row.Hl element with numbers that
-// constitute the color the byte with the same index in Row.Render
+// constitute the color the rune with the same index in Row.Render
 // should have when displayed.
 func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNextRow bool) {
 	aRow.Hl = make([]byte, aRow.Rsize)
@@ -93,7 +106,7 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 	mce := syntax.multiLineCommentEnd
 	prevSep := true
 	inComment := inCommentNow
-	var inString byte
+	var inString rune
 	var skip int
 	for i, c := range aRow.Render {
 		if skip > 0 {
@@ -101,7 +114,7 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 			continue
 		}
 		if inString == 0 && len(scs) > 0 && !inComment {
-			if bytes.HasPrefix(aRow.Render[i:], scs) {
+			if row.HasPrefix(aRow.Render[i:], scs) {
 				for j := i; j < aRow.Rsize; j++ {
 					aRow.Hl[j] = HL_COMMENT
 				}
@@ -111,7 +124,7 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 		if inString == 0 && len(mcs) > 0 && len(mce) > 0 {
 			if inComment {
 				aRow.Hl[i] = HL_MLCOMMENT
-				if bytes.HasPrefix(aRow.Render[i:], mce) {
+				if row.HasPrefix(aRow.Render[i:], mce) {
 					for l := i; l < i+len(mce); l++ {
 						aRow.Hl[l] = HL_MLCOMMENT
 					}
@@ -120,7 +133,7 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 					prevSep = true
 				}
 				continue
-			} else if bytes.HasPrefix(aRow.Render[i:], mcs) {
+			} else if row.HasPrefix(aRow.Render[i:], mcs) {
 				for l := i; l < i+len(mcs); l++ {
 					aRow.Hl[l] = HL_MLCOMMENT
 				}
@@ -152,7 +165,7 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 			}
 		}
 		if (syntax.flags & HL_HIGHLIGHT_NUMBERS) == HL_HIGHLIGHT_NUMBERS {
-			if unicode.IsDigit(rune(c)) &&
+			if unicode.IsDigit(c) &&
 				(prevSep || prevHl == HL_NUMBER) ||
 				(c == '.' && prevHl == HL_NUMBER) {
 				aRow.Hl[i] = HL_NUMBER
@@ -164,17 +177,17 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 			var j int
 			var skw string
 			for j, skw = range syntax.keywords {
-				kw := []byte(skw)
+				kw := []rune(skw)
 				var color byte = HL_KEYWORD1
-				idx := bytes.LastIndexByte(kw, '|')
+				idx := strings.LastIndexByte(skw, '|')
 				if idx > 0 {
 					kw = kw[:idx]
 					color = HL_KEYWORD2
 				}
 				klen := len(kw)
-				if bytes.HasPrefix(aRow.Render[i:], kw) &&
+				if row.HasPrefix(aRow.Render[i:], kw) &&
 					(len(aRow.Render[i:]) == klen ||
-						isSeparator(aRow.Render[i+klen])) {
+						IsSeparator(aRow.Render[i+klen])) {
 					for l := i; l < i+klen; l++ {
 						aRow.Hl[l] = color
 					}
@@ -187,7 +200,7 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 				continue
 			}
 		}
-		prevSep = isSeparator(c)
+		prevSep = IsSeparator(c)
 	}
 
 	updateNextRow = aRow.HlOpenComment != inComment
@@ -195,26 +208,116 @@ func (syntax *Syntax) UpdateSyntax(aRow *row.Row, inCommentNow bool) (updateNext
 	return updateNextRow
 }
 
+// IndentsAfter reports whether the line after one holding
+// text line should get one more level of indentation.
+func (syntax *Syntax) IndentsAfter(line []rune) bool {
+	if syntax == nil {
+		return false
+	}
+	for i := len(line) - 1; i >= 0; i-- {
+		if !unicode.IsSpace(line[i]) {
+			return strings.ContainsRune(syntax.indentAfter, line[i])
+		}
+	}
+	return false
+}
+
+// DedentsOn reports whether typing c at the start of a line
+// should take a level of indentation off the line.
+func (syntax *Syntax) DedentsOn(c rune) bool {
+	return syntax != nil && strings.ContainsRune(syntax.dedentOn, c)
+}
+
+// Colors map the names of highlight types to the color numbers
+// used in VT-100 escape sequences.
+type Colors map[string]int
+
+// hlNames are the names Colors use for the highlight types.
+var hlNames = [...]string{
+	HL_NORMAL:    "normal",
+	HL_COMMENT:   "comment",
+	HL_MLCOMMENT: "mlcomment",
+	HL_KEYWORD1:  "keyword1",
+	HL_KEYWORD2:  "keyword2",
+	HL_STRING:    "string",
+	HL_NUMBER:    "number",
+	HL_MATCH:     "match",
+}
+
+// colorNames are names for the colors, so people
+// don't have to know the numbers.
+var colorNames = map[string]int{
+	"black": 30, "red": 31, "green": 32, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+}
+
+// defaultColor is the number for the terminal's own text color.
+const defaultColor = 39
+
+// DefaultColors returns the colors kilo has always used.
+func DefaultColors() Colors {
+	return Colors{
+		"normal":    defaultColor,
+		"comment":   36,
+		"mlcomment": 36,
+		"keyword1":  32,
+		"keyword2":  33,
+		"string":    35,
+		"number":    31,
+		"match":     34,
+	}
+}
+
+// Set makes highlight type name show in color, which is a color
+// name, maybe with "bright-" in front, "default" for the terminal's
+// own text color, or a color number, 30 to 37, 39 or 90 to 97.
+func (colors Colors) Set(name, color string) error {
+	if _, ok := DefaultColors()[name]; !ok {
+		return fmt.Errorf("no highlight type called %q", name)
+	}
+	if color == "default" || color == strconv.Itoa(defaultColor) {
+		colors[name] = defaultColor
+		return nil
+	}
+	n, ok := colorNames[strings.TrimPrefix(color, "bright-")]
+	if ok && strings.HasPrefix(color, "bright-") {
+		n += 60
+	}
+	if !ok {
+		var err error
+		if n, err = strconv.Atoi(color); err != nil {
+			return fmt.Errorf("no color called %q", color)
+		}
+		if n < 30 || n > 37 && n < 90 || n > 97 {
+			return fmt.Errorf("color numbers go from 30 to 37, 39 and 90 to 97, not %d", n)
+		}
+	}
+	colors[name] = n
+	return nil
+}
+
 // SyntaxToColor maps byte values from Row.Hl to the color numbers
 // used in VT-100 escape sequences.
-func SyntaxToColor(hl byte) int {
-	switch hl {
-	case HL_COMMENT, HL_MLCOMMENT:
-		return 36
-	case HL_KEYWORD1:
-		return 32
-	case HL_KEYWORD2:
-		return 33
-	case HL_STRING:
-		return 35
-	case HL_NUMBER:
-		return 31
-	case HL_MATCH:
-		return 34
+func (colors Colors) SyntaxToColor(hl byte) int {
+	if int(hl) < len(hlNames) {
+		if n, ok := colors[hlNames[hl]]; ok {
+			return n
+		}
 	}
 	return 37
 }
 
+// FindFiletype returns the syntax for the filetype called
+// name, in any case, or nil if there isn't one.
+func FindFiletype(name string) *Syntax {
+	for _, s := range hldb {
+		if strings.EqualFold(s.Filetype, name) {
+			return s
+		}
+	}
+	return nil
+}
+
 // SelectSyntaxHighlight selects an element of array hldb (*Syntax)
 // based on the suffix of the filename argument.
 func SelectSyntaxHighlight(filename string) *Syntax {
@@ -0,0 +1,376 @@
+package keyboard
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* Terminals send most keys that aren't characters as escape
+ * sequences: ESC [ (CSI) or ESC O (SS3), maybe some numeric
+ * parameters separated by ';', and a final byte. xterm, and
+ * the terminals that copy it, put the modifiers in the second
+ * parameter, as 1 plus the Modifier bits, as in ESC [ 1 ; 5 C
+ * for Ctrl-Right. rxvt and the Linux console have their own
+ * ideas, which the tables below also cover.
+ */
+
+// byteSource returns the next byte of a keypress, or false if
+// no more bytes arrive soon enough to be part of it.
+type byteSource func() (byte, bool)
+
+// csiKeys are the keys for CSI sequences ending in a letter.
+var csiKeys = map[byte]int{
+	'A': ARROW_UP,
+	'B': ARROW_DOWN,
+	'C': ARROW_RIGHT,
+	'D': ARROW_LEFT,
+	'H': HOME_KEY,
+	'F': END_KEY,
+	'P': F1,
+	'Q': F2,
+	'R': F3,
+	'S': F4,
+}
+
+// ss3Keys are the keys for SS3 sequences, which xterm sends for
+// arrows in application mode, and for F1 through F4.
+var ss3Keys = map[byte]int{
+	'A': ARROW_UP,
+	'B': ARROW_DOWN,
+	'C': ARROW_RIGHT,
+	'D': ARROW_LEFT,
+	'H': HOME_KEY,
+	'F': END_KEY,
+	'P': F1,
+	'Q': F2,
+	'R': F3,
+	'S': F4,
+	'M': '\r', // keypad Enter
+}
+
+// rxvtArrows are the arrows that rxvt sends with Shift as
+// CSI and a lower case letter, and with Ctrl as SS3 and one.
+var rxvtArrows = map[byte]int{
+	'a': ARROW_UP,
+	'b': ARROW_DOWN,
+	'c': ARROW_RIGHT,
+	'd': ARROW_LEFT,
+}
+
+// linuxFKeys are the keys the Linux console sends as ESC [ [ and
+// a letter.
+var linuxFKeys = map[byte]int{
+	'A': F1,
+	'B': F2,
+	'C': F3,
+	'D': F4,
+	'E': F5,
+}
+
+// tildeKeys are the keys for CSI sequences that give the key as
+// a number and end in '~', or for rxvt, in a character that says
+// which modifiers were down.
+var tildeKeys = map[int]int{
+	1:  HOME_KEY,
+	2:  INSERT_KEY,
+	3:  DEL_KEY,
+	4:  END_KEY,
+	5:  PAGE_UP,
+	6:  PAGE_DOWN,
+	7:  HOME_KEY,
+	8:  END_KEY,
+	11: F1,
+	12: F2,
+	13: F3,
+	14: F4,
+	15: F5,
+	17: F6,
+	18: F7,
+	19: F8,
+	20: F9,
+	21: F10,
+	23: F11,
+	24: F12,
+}
+
+// rxvtTildeMods are the modifiers that go with rxvt's
+// replacements for the final '~'.
+var rxvtTildeMods = map[byte]Modifier{
+	'~': 0,
+	'$': Shift,
+	'^': Ctrl,
+	'@': Ctrl | Shift,
+}
+
+// decode turns the first byte of a keypress, and whatever bytes
+// follow it from next, into an Event.
+func decode(first byte, next byteSource) Event {
+	switch {
+	case first == ESCAPE:
+		return decodeEscape(next)
+	case first >= utf8.RuneSelf:
+		return Event{Key: readUTF8(first, next)}
+	}
+	return Event{Key: int(first)}
+}
+
+// readUTF8 collects the rest of a multi-byte UTF-8 character
+// whose first byte is lead, and returns it as a single rune.
+func readUTF8(lead byte, next byteSource) int {
+	seq := []byte{lead}
+	for !utf8.FullRune(seq) {
+		b, ok := next()
+		if !ok {
+			break
+		}
+		seq = append(seq, b)
+	}
+	r, _ := utf8.DecodeRune(seq)
+	return int(r)
+}
+
+// decodeEscape decodes whatever comes after an ESC. An ESC with
+// nothing right after it is the Escape key, and one followed by
+// anything that doesn't start an escape sequence is that key
+// with Alt held down, the way terminals send Alt.
+func decodeEscape(next byteSource) Event {
+	b, ok := next()
+	if !ok {
+		return Event{Key: ESCAPE}
+	}
+	switch b {
+	case '[':
+		return decodeCSI(next)
+	case 'O':
+		return decodeSS3(next)
+	case ']':
+		return readOSC(next)
+	case ESCAPE:
+		// rxvt sends Alt with a special key as another ESC in front.
+		ev := decodeEscape(next)
+		if ev.Key != ESCAPE {
+			ev.Mod |= Alt
+		}
+		return ev
+	}
+	ev := decode(b, next)
+	ev.Mod |= Alt
+	return ev
+}
+
+// readParams reads parameter bytes up to the final byte of an
+// escape sequence, which it returns along with the parameters.
+// If the sequence stops short, it returns false.
+func readParams(next byteSource) (params string, final byte, ok bool) {
+	var p []byte
+	for {
+		b, ok := next()
+		if !ok {
+			return string(p), 0, false
+		}
+		if b < 0x30 || b > 0x3f {
+			return string(p), b, true
+		}
+		p = append(p, b)
+	}
+}
+
+// numbers splits escape sequence parameters into numbers. Missing
+// or odd parameters come out as 0.
+func numbers(params string) []int {
+	if params == "" {
+		return nil
+	}
+	fields := strings.Split(params, ";")
+	nums := make([]int, len(fields))
+	for i, f := range fields {
+		nums[i], _ = strconv.Atoi(f)
+	}
+	return nums
+}
+
+// xtermModifier turns an xterm modifier parameter into Modifier flags.
+func xtermModifier(n int) Modifier {
+	if n < 2 {
+		return 0
+	}
+	return Modifier(n-1) & (Shift | Alt | Ctrl | Meta)
+}
+
+func decodeCSI(next byteSource) Event {
+	params, final, ok := readParams(next)
+	if !ok {
+		if params == "" {
+			// Just ESC [, so Alt-[.
+			return Event{Key: '[', Mod: Alt}
+		}
+		return Event{Key: UNKNOWN}
+	}
+	nums := numbers(params)
+	var mod Modifier
+	if len(nums) > 1 {
+		mod = xtermModifier(nums[1])
+	}
+
+	if final == 'u' && len(nums) > 0 {
+		return otherKey(nums[0], mod)
+	}
+	if extra, isTilde := rxvtTildeMods[final]; isTilde && len(nums) > 0 {
+		if nums[0] == pasteStart {
+			return readPaste(next)
+		}
+		if nums[0] == modifyOtherKeys && len(nums) > 2 {
+			return otherKey(nums[2], mod|extra)
+		}
+		if key, ok := tildeKeys[nums[0]]; ok {
+			return Event{Key: key, Mod: mod | extra}
+		}
+		return Event{Key: UNKNOWN}
+	}
+	if params == "" {
+		switch final {
+		case '[':
+			b, _ := next()
+			if key, ok := linuxFKeys[b]; ok {
+				return Event{Key: key}
+			}
+			return Event{Key: UNKNOWN}
+		case 'Z':
+			return Event{Key: '\t', Mod: Shift}
+		case 'M':
+			// An old-style mouse report: three more bytes.
+			next()
+			next()
+			next()
+			return Event{Key: UNKNOWN}
+		}
+		if key, ok := rxvtArrows[final]; ok {
+			return Event{Key: key, Mod: Shift}
+		}
+	}
+	if key, ok := csiKeys[final]; ok {
+		return Event{Key: key, Mod: mod}
+	}
+	return Event{Key: UNKNOWN}
+}
+
+// modifyOtherKeys is the number xterm puts first when it sends
+// a character with modifiers as ESC [ 27 ; modifiers ; code ~,
+// the way it can send keys like Ctrl-Backspace that otherwise
+// come as a plain control character.
+const modifyOtherKeys = 27
+
+// otherKey makes an Event for a character that came with its
+// modifiers in an escape sequence, from xterm's modifyOtherKeys
+// or the CSI u form other terminals use. A character that has
+// a control character for Ctrl comes out as that, and a letter
+// with Shift as the capital, like they do when typed plainly.
+func otherKey(code int, mod Modifier) Event {
+	c := unicode.ToUpper(rune(code))
+	switch {
+	case mod&Ctrl != 0 && code != BACKSPACE && (c == ' ' || c >= '@' && c <= '_'):
+		return Event{Key: int(c) & 0x1f, Mod: mod &^ Ctrl}
+	case mod&Shift != 0 && unicode.IsLetter(rune(code)):
+		return Event{Key: int(c), Mod: mod &^ Shift}
+	}
+	return Event{Key: code, Mod: mod}
+}
+
+func decodeSS3(next byteSource) Event {
+	params, final, ok := readParams(next)
+	if !ok {
+		if params == "" {
+			// Just ESC O, so Alt-O.
+			return Event{Key: 'O', Mod: Alt}
+		}
+		return Event{Key: UNKNOWN}
+	}
+	// Some terminals send the modifier as the only parameter.
+	var mod Modifier
+	if nums := numbers(params); len(nums) > 0 {
+		mod = xtermModifier(nums[len(nums)-1])
+	}
+	if key, ok := ss3Keys[final]; ok {
+		return Event{Key: key, Mod: mod}
+	}
+	if key, ok := rxvtArrows[final]; ok {
+		return Event{Key: key, Mod: Ctrl}
+	}
+	return Event{Key: UNKNOWN}
+}
+
+// With bracketed paste mode on (see tty.EnableRawMode), the
+// terminal sends ESC [ 200 ~ before pasted text, and ESC [ 201 ~
+// after it, so pasted text doesn't look like typing.
+const (
+	pasteStart = 200
+	pasteEnd   = "\x1b[201~"
+)
+
+// The text of the last paste.
+var pasted string
+
+// PastedText returns the text that came in as the most recent PASTE
+// key, with the line endings changed to "\n".
+func PastedText() string {
+	return pasted
+}
+
+// readPaste collects pasted text up to the end marker. Big pastes
+// can arrive in pieces, so it waits a little while for more before
+// deciding the end marker got lost.
+func readPaste(next byteSource) Event {
+	var text []byte
+	for timeouts := 0; timeouts < idleTimeouts && !bytes.HasSuffix(text, []byte(pasteEnd)); {
+		b, ok := next()
+		if !ok {
+			timeouts++
+			continue
+		}
+		timeouts = 0
+		text = append(text, b)
+	}
+	text = bytes.TrimSuffix(text, []byte(pasteEnd))
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	pasted = string(bytes.ReplaceAll(text, []byte("\r"), []byte("\n")))
+	return Event{Key: PASTE}
+}
+
+// The last operating system command string the terminal sent.
+var osc string
+
+// OSCString returns the text of the operating system command
+// string that came in as the most recent OSC key, without the
+// ESC ] in front or the BEL or ST on the end. Terminals send
+// these to answer queries, like one for what's on the clipboard.
+func OSCString() string {
+	return osc
+}
+
+// readOSC reads the rest of an operating system command string,
+// which started with ESC ].
+func readOSC(next byteSource) Event {
+	var s []byte
+	for {
+		b, ok := next()
+		if !ok {
+			if len(s) == 0 {
+				// Just ESC ], so Alt-].
+				return Event{Key: ']', Mod: Alt}
+			}
+			return Event{Key: UNKNOWN}
+		}
+		switch {
+		case b == '\x07':
+			osc = string(s)
+			return Event{Key: OSC}
+		case b == '\\' && len(s) > 0 && s[len(s)-1] == ESCAPE:
+			osc = string(s[:len(s)-1])
+			return Event{Key: OSC}
+		}
+		s = append(s, b)
+	}
+}
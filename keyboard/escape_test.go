@@ -0,0 +1,171 @@
+package keyboard
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// bytesOf returns a byteSource that hands out s a byte at a time,
+// then says nothing more is coming.
+func bytesOf(s string) (byteSource, func() string) {
+	i := 0
+	next := func() (byte, bool) {
+		if i >= len(s) {
+			return 0, false
+		}
+		i++
+		return s[i-1], true
+	}
+	return next, func() string { return s[i:] }
+}
+
+var decodeTests = []struct {
+	name string
+	in   string
+	want Event
+}{
+	// Plain keys.
+	{"letter", "a", Event{Key: 'a'}},
+	{"enter", "\r", Event{Key: '\r'}},
+	{"backspace", "\x7f", Event{Key: BACKSPACE}},
+	{"ctrl-s", "\x13", Event{Key: CTRL_S}},
+	{"utf-8", "é", Event{Key: 'é'}},
+	{"wide utf-8", "世", Event{Key: '世'}},
+	{"escape", "\x1b", Event{Key: ESCAPE}},
+	{"alt-x", "\x1bx", Event{Key: 'x', Mod: Alt}},
+	{"alt-backspace", "\x1b\x7f", Event{Key: BACKSPACE, Mod: Alt}},
+	{"alt-utf-8", "\x1bé", Event{Key: 'é', Mod: Alt}},
+
+	// xterm.
+	{"xterm up", "\x1b[A", Event{Key: ARROW_UP}},
+	{"xterm end", "\x1b[F", Event{Key: END_KEY}},
+	{"xterm ctrl-right", "\x1b[1;5C", Event{Key: ARROW_RIGHT, Mod: Ctrl}},
+	{"xterm shift-left", "\x1b[1;2D", Event{Key: ARROW_LEFT, Mod: Shift}},
+	{"xterm alt-up", "\x1b[1;3A", Event{Key: ARROW_UP, Mod: Alt}},
+	{"xterm ctrl-shift-home", "\x1b[1;6H", Event{Key: HOME_KEY, Mod: Ctrl | Shift}},
+	{"xterm meta-down", "\x1b[1;9B", Event{Key: ARROW_DOWN, Mod: Meta}},
+	{"xterm delete", "\x1b[3~", Event{Key: DEL_KEY}},
+	{"xterm ctrl-delete", "\x1b[3;5~", Event{Key: DEL_KEY, Mod: Ctrl}},
+	{"xterm page up", "\x1b[5~", Event{Key: PAGE_UP}},
+	{"xterm f5", "\x1b[15~", Event{Key: F5}},
+	{"xterm shift-f12", "\x1b[24;2~", Event{Key: F12, Mod: Shift}},
+	{"xterm f1", "\x1bOP", Event{Key: F1}},
+	{"xterm ctrl-f1", "\x1b[1;5P", Event{Key: F1, Mod: Ctrl}},
+	{"xterm application up", "\x1bOA", Event{Key: ARROW_UP}},
+	{"xterm keypad enter", "\x1bOM", Event{Key: '\r'}},
+	{"xterm shift-tab", "\x1b[Z", Event{Key: '\t', Mod: Shift}},
+
+	// rxvt.
+	{"rxvt home", "\x1b[7~", Event{Key: HOME_KEY}},
+	{"rxvt end", "\x1b[8~", Event{Key: END_KEY}},
+	{"rxvt f1", "\x1b[11~", Event{Key: F1}},
+	{"rxvt shift-up", "\x1b[a", Event{Key: ARROW_UP, Mod: Shift}},
+	{"rxvt ctrl-right", "\x1bOc", Event{Key: ARROW_RIGHT, Mod: Ctrl}},
+	{"rxvt ctrl-delete", "\x1b[3^", Event{Key: DEL_KEY, Mod: Ctrl}},
+	{"rxvt shift-page up", "\x1b[5$", Event{Key: PAGE_UP, Mod: Shift}},
+	{"rxvt ctrl-shift-home", "\x1b[7@", Event{Key: HOME_KEY, Mod: Ctrl | Shift}},
+	{"rxvt alt-up", "\x1b\x1b[A", Event{Key: ARROW_UP, Mod: Alt}},
+	{"rxvt alt-delete", "\x1b\x1b[3~", Event{Key: DEL_KEY, Mod: Alt}},
+
+	// Linux console.
+	{"linux f1", "\x1b[[A", Event{Key: F1}},
+	{"linux f5", "\x1b[[E", Event{Key: F5}},
+	{"linux home", "\x1b[1~", Event{Key: HOME_KEY}},
+	{"linux end", "\x1b[4~", Event{Key: END_KEY}},
+	{"linux insert", "\x1b[2~", Event{Key: INSERT_KEY}},
+
+	// tmux, which passes on xterm's keys, and sends some of its own
+	// in the older SS3 form with the modifier as the only parameter.
+	{"tmux ctrl-left", "\x1b[1;5D", Event{Key: ARROW_LEFT, Mod: Ctrl}},
+	{"tmux home", "\x1b[1~", Event{Key: HOME_KEY}},
+	{"tmux application home", "\x1bOH", Event{Key: HOME_KEY}},
+	{"tmux ss3 ctrl-right", "\x1bO5C", Event{Key: ARROW_RIGHT, Mod: Ctrl}},
+	{"tmux ss3 shift-f1", "\x1bO2P", Event{Key: F1, Mod: Shift}},
+
+	// Characters with modifiers, from xterm's modifyOtherKeys and
+	// from terminals that use the CSI u form.
+	{"xterm ctrl-backspace", "\x1b[27;5;127~", Event{Key: BACKSPACE, Mod: Ctrl}},
+	{"csi u ctrl-backspace", "\x1b[127;5u", Event{Key: BACKSPACE, Mod: Ctrl}},
+	{"csi u ctrl-s", "\x1b[115;5u", Event{Key: CTRL_S}},
+	{"csi u ctrl-space", "\x1b[32;5u", Event{Key: CTRL_SPACE}},
+	{"csi u shift-a", "\x1b[97;2u", Event{Key: 'A'}},
+	{"csi u alt-enter", "\x1b[13;3u", Event{Key: '\r', Mod: Alt}},
+
+	// Sequences that stop short.
+	{"just csi", "\x1b[", Event{Key: '[', Mod: Alt}},
+	{"just ss3", "\x1bO", Event{Key: 'O', Mod: Alt}},
+	{"just osc", "\x1b]", Event{Key: ']', Mod: Alt}},
+	{"csi with no final byte", "\x1b[1;5", Event{Key: UNKNOWN}},
+	{"ss3 with no final byte", "\x1bO5", Event{Key: UNKNOWN}},
+	{"linux f-key with no letter", "\x1b[[", Event{Key: UNKNOWN}},
+	{"osc with no end", "\x1b]52;c;YQ==", Event{Key: UNKNOWN}},
+	{"cut-off utf-8", "\xc3", Event{Key: utf8.RuneError}},
+
+	// Sequences nobody knows.
+	{"unknown tilde key", "\x1b[99~", Event{Key: UNKNOWN}},
+	{"unknown csi final", "\x1b[X", Event{Key: UNKNOWN}},
+	{"unknown ss3 final", "\x1bOX", Event{Key: UNKNOWN}},
+	{"unknown linux f-key", "\x1b[[Z", Event{Key: UNKNOWN}},
+	{"old mouse report", "\x1b[M !!", Event{Key: UNKNOWN}},
+}
+
+func TestDecode(t *testing.T) {
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, rest := bytesOf(tt.in[1:])
+			if got := decode(tt.in[0], next); got != tt.want {
+				t.Errorf("decode(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if r := rest(); r != "" {
+				t.Errorf("decode(%q) left %q unread", tt.in, r)
+			}
+		})
+	}
+}
+
+func TestDecodePaste(t *testing.T) {
+	in := "\x1b[200~one\r\ntwo\rthree\x1b[201~x"
+	next, rest := bytesOf(in[1:])
+	if got := decode(in[0], next); got != (Event{Key: PASTE}) {
+		t.Fatalf("decode(%q) = %+v, want PASTE", in, got)
+	}
+	if got, want := PastedText(), "one\ntwo\nthree"; got != want {
+		t.Errorf("PastedText() = %q, want %q", got, want)
+	}
+	if r := rest(); r != "x" {
+		t.Errorf("left %q unread, want %q", r, "x")
+	}
+}
+
+func TestDecodeOSC(t *testing.T) {
+	for _, in := range []string{
+		"\x1b]52;c;YQ==\x07",
+		"\x1b]52;c;YQ==\x1b\\",
+	} {
+		next, _ := bytesOf(in[1:])
+		if got := decode(in[0], next); got != (Event{Key: OSC}) {
+			t.Errorf("decode(%q) = %+v, want OSC", in, got)
+			continue
+		}
+		if got, want := OSCString(), "52;c;YQ=="; got != want {
+			t.Errorf("decode(%q): OSCString() = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCtrlBackspaceIsNotCtrlH(t *testing.T) {
+	if CTRL_BACKSPACE == CTRL_H {
+		t.Fatal("CTRL_BACKSPACE is CTRL_H, which many terminals send for Backspace")
+	}
+	if got := (Event{Key: BACKSPACE, Mod: Ctrl}).Code(); got != CTRL_BACKSPACE {
+		t.Errorf("Ctrl with Backspace = %#x, want CTRL_BACKSPACE", got)
+	}
+	for name, want := range map[string]int{"Ctrl-Backspace": CTRL_BACKSPACE, "Ctrl-H": CTRL_H, "Backspace": BACKSPACE} {
+		if got, err := ParseKey(name); err != nil || got != want {
+			t.Errorf("ParseKey(%q) = %#x, %v, want %#x", name, got, err, want)
+		}
+		if got := KeyName(want); got != name {
+			t.Errorf("KeyName(%#x) = %q, want %q", want, got, name)
+		}
+	}
+}
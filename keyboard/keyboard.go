@@ -2,116 +2,185 @@ package keyboard
 
 import (
 	"os"
+	"os/signal"
+	"syscall"
+	"unicode/utf8"
 )
 
+// Keys that aren't characters get values past the last
+// Unicode code point, so ReadKey can return either.
+const specialKey = int(utf8.MaxRune)
+
 /* Integer versions of usually single-byte, but
  * sometimes multi-byte, keypresses.
  */
 const (
 	BACKSPACE   = 127
-	ARROW_LEFT  = 1000 + iota
-	ARROW_RIGHT = 1000 + iota
-	ARROW_UP    = 1000 + iota
-	ARROW_DOWN  = 1000 + iota
-	DEL_KEY     = 1000 + iota
-	HOME_KEY    = 1000 + iota
-	END_KEY     = 1000 + iota
-	PAGE_UP     = 1000 + iota
-	PAGE_DOWN   = 1000 + iota
+	ARROW_LEFT  = specialKey + iota
+	ARROW_RIGHT = specialKey + iota
+	ARROW_UP    = specialKey + iota
+	ARROW_DOWN  = specialKey + iota
+	DEL_KEY     = specialKey + iota
+	HOME_KEY    = specialKey + iota
+	END_KEY     = specialKey + iota
+	PAGE_UP     = specialKey + iota
+	PAGE_DOWN   = specialKey + iota
+	INSERT_KEY  = specialKey + iota
+	F1          = specialKey + iota
+	F2          = specialKey + iota
+	F3          = specialKey + iota
+	F4          = specialKey + iota
+	F5          = specialKey + iota
+	F6          = specialKey + iota
+	F7          = specialKey + iota
+	F8          = specialKey + iota
+	F9          = specialKey + iota
+	F10         = specialKey + iota
+	F11         = specialKey + iota
+	F12         = specialKey + iota
+	RESIZE      = specialKey + iota
+	IDLE        = specialKey + iota
+	OSC         = specialKey + iota
+	PASTE       = specialKey + iota
+	UNKNOWN     = specialKey + iota
+	CTRL_SPACE  = 0
+	CTRL_A      = 'a' & 0x1f
+	CTRL_B      = 'b' & 0x1f
+	CTRL_C      = 'c' & 0x1f
+	CTRL_D      = 'd' & 0x1f
+	CTRL_G      = 'g' & 0x1f
 	CTRL_H      = 'h' & 0x1f
+	CTRL_K      = 'k' & 0x1f
 	CTRL_L      = 'l' & 0x1f
+	CTRL_E      = 'e' & 0x1f
 	CTRL_F      = 'f' & 0x1f
+	CTRL_N      = 'n' & 0x1f
+	CTRL_O      = 'o' & 0x1f
+	CTRL_P      = 'p' & 0x1f
 	CTRL_Q      = 'q' & 0x1f
+	CTRL_R      = 'r' & 0x1f
 	CTRL_S      = 's' & 0x1f
+	CTRL_T      = 't' & 0x1f
+	CTRL_U      = 'u' & 0x1f
+	CTRL_V      = 'v' & 0x1f
+	CTRL_W      = 'w' & 0x1f
+	CTRL_X      = 'x' & 0x1f
+	CTRL_Y      = 'y' & 0x1f
+	CTRL_Z      = 'z' & 0x1f
 	ESCAPE      = '\x1b'
 )
 
+// Modifier flags say which modifier keys were held down with
+// a key. They're the bits xterm puts in escape sequences.
+type Modifier int
+
+const (
+	Shift Modifier = 1 << iota
+	Alt
+	Ctrl
+	Meta
+)
+
+// ReadKey puts a key's modifiers in the bits from here up,
+// clear of every rune and special key.
+const modShift = 24
+
+// Shifted keys, for selecting text.
+const (
+	SHIFT_LEFT  = ARROW_LEFT | int(Shift)<<modShift
+	SHIFT_RIGHT = ARROW_RIGHT | int(Shift)<<modShift
+	SHIFT_UP    = ARROW_UP | int(Shift)<<modShift
+	SHIFT_DOWN  = ARROW_DOWN | int(Shift)<<modShift
+	SHIFT_HOME  = HOME_KEY | int(Shift)<<modShift
+	SHIFT_END   = END_KEY | int(Shift)<<modShift
+)
+
+// Keys for moving and deleting by words, and jumping to the ends of
+// the file. Most terminals send Ctrl-H for Ctrl-Backspace, and many
+// send it for Backspace too, so Ctrl-H is Backspace; CTRL_BACKSPACE
+// only comes from terminals that send it as an escape sequence.
+const (
+	CTRL_LEFT      = ARROW_LEFT | int(Ctrl)<<modShift
+	CTRL_RIGHT     = ARROW_RIGHT | int(Ctrl)<<modShift
+	CTRL_HOME      = HOME_KEY | int(Ctrl)<<modShift
+	CTRL_END       = END_KEY | int(Ctrl)<<modShift
+	CTRL_DEL       = DEL_KEY | int(Ctrl)<<modShift
+	CTRL_BACKSPACE = BACKSPACE | int(Ctrl)<<modShift
+	ALT_BACKSPACE  = BACKSPACE | int(Alt)<<modShift
+)
+
+// Event is a single keypress: a key, which is a rune or one of
+// the constants above, and the modifiers held down with it. Control
+// characters come as themselves, like CTRL_S, not as a letter with
+// Ctrl, since that's all a terminal says about them.
+type Event struct {
+	Key int
+	Mod Modifier
+}
+
+// Code packs ev into a single int, the way ReadKey returns keys.
+func (ev Event) Code() int {
+	return ev.Key | int(ev.Mod)<<modShift
+}
+
+// Decode unpacks a key that ReadKey returned.
+func Decode(code int) Event {
+	return Event{Key: code & (1<<modShift - 1), Mod: Modifier(code >> modShift)}
+}
+
+var resized = make(chan os.Signal, 1)
+
+// WatchResize arranges for ReadKey to return RESIZE when the
+// terminal window changes size. The signal arrives on another
+// goroutine, so it's handed over on a channel that ReadKey
+// checks every time a read from stdin times out.
+func WatchResize() {
+	signal.Notify(resized, syscall.SIGWINCH)
+}
+
+// Reads from stdin time out every tenth of a second (see
+// tty.EnableRawMode), so this many timeouts in a row means
+// the user hasn't typed anything for two seconds.
+const idleTimeouts = 20
+
 // ReadKey reads a possibly multi-byte keypress from stdin, returning an
-// int (the const values above) that represents the keypress.
+// int (the const values above, or a rune, with any modifiers packed in
+// by Event.Code) that represents the keypress. It returns IDLE every
+// so often while waiting for a key.
 func ReadKey() (int, error) {
+	ev, err := ReadEvent()
+	return ev.Code(), err
+}
+
+// ReadEvent does the work of ReadKey, returning the keypress
+// as an Event.
+func ReadEvent() (Event, error) {
 	var buffer [1]byte
 	var cc int
 	var err error
+	timeouts := 0
 	for cc, err = os.Stdin.Read(buffer[:]); cc != 1; cc, err = os.Stdin.Read(buffer[:]) {
+		select {
+		case <-resized:
+			return Event{Key: RESIZE}, nil
+		default:
+		}
+		if timeouts++; timeouts == idleTimeouts {
+			return Event{Key: IDLE}, nil
+		}
 	}
 	if err != nil {
-		return -1, err
-	}
-	if buffer[0] == ESCAPE {
-		return readEscapeSequence()
-	}
-	return int(buffer[0]), nil
-}
-
-func arrowKeyDecode(k byte) (int, error) {
-	switch k {
-	case 'A':
-		return ARROW_UP, nil
-	case 'B':
-		return ARROW_DOWN, nil
-	case 'C':
-		return ARROW_RIGHT, nil
-	case 'D':
-		return ARROW_LEFT, nil
-	case 'H':
-		return HOME_KEY, nil
-	case 'F':
-		return END_KEY, nil
+		return Event{Key: -1}, err
 	}
-	return int(k), nil
+	return decode(buffer[0], stdinByte), nil
 }
 
-func tildeDecode(b byte) (int, error) {
-	switch b {
-	case '1':
-		return HOME_KEY, nil
-	case '3':
-		return DEL_KEY, nil
-	case '4':
-		return END_KEY, nil
-	case '5':
-		return PAGE_UP, nil
-	case '6':
-		return PAGE_DOWN, nil
-	case '7':
-		return HOME_KEY, nil
-	case '8':
-		return END_KEY, nil
-	}
-	return int(b), nil
-}
-
-func readEscapeSequence() (int, error) {
-	var seq [2]byte
+// stdinByte reads one byte from stdin, returning false if
+// none arrives before the read times out.
+func stdinByte() (byte, bool) {
 	var buffer [1]byte
-	var cc int
-
-	if cc, _ = os.Stdin.Read(seq[:]); cc != 2 {
-		return ESCAPE, nil
+	if cc, _ := os.Stdin.Read(buffer[:]); cc != 1 {
+		return 0, false
 	}
-
-	if seq[0] == '[' {
-		if seq[1] >= '0' && seq[1] <= '9' {
-			if cc, _ = os.Stdin.Read(buffer[:]); cc != 1 {
-				return '\x1b', nil
-			}
-			if buffer[0] == '~' {
-				return tildeDecode(seq[1])
-			}
-			// XXX - falls all the way through
-		} else {
-			return arrowKeyDecode(seq[1])
-		}
-		// XXX - falls all the way through
-	} else if seq[0] == '0' {
-		switch seq[1] {
-		case 'H':
-			return HOME_KEY, nil
-		case 'F':
-			return END_KEY, nil
-		}
-		// XXX - is it correct to fall through?
-	}
-
-	return ESCAPE, nil
+	return buffer[0], true
 }
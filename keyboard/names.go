@@ -0,0 +1,174 @@
+package keyboard
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* Keys have names like "Ctrl-S", "Alt-Left", "Shift-Tab" or
+ * "PageUp", for binding them to commands in the config file.
+ * Control characters are Ctrl with a letter, or with one of
+ * "@[\]^_" for the rest, and Ctrl-Space for NUL. Letters are
+ * as typed, so Alt-f is Alt with the f key, and Alt-F is Alt
+ * with Shift and f, but with Ctrl, case doesn't matter.
+ */
+
+// keyNames are the names of keys that aren't printable characters.
+var keyNames = map[int]string{
+	ARROW_LEFT:  "Left",
+	ARROW_RIGHT: "Right",
+	ARROW_UP:    "Up",
+	ARROW_DOWN:  "Down",
+	DEL_KEY:     "Delete",
+	HOME_KEY:    "Home",
+	END_KEY:     "End",
+	PAGE_UP:     "PageUp",
+	PAGE_DOWN:   "PageDown",
+	INSERT_KEY:  "Insert",
+	F1:          "F1",
+	F2:          "F2",
+	F3:          "F3",
+	F4:          "F4",
+	F5:          "F5",
+	F6:          "F6",
+	F7:          "F7",
+	F8:          "F8",
+	F9:          "F9",
+	F10:         "F10",
+	F11:         "F11",
+	F12:         "F12",
+	BACKSPACE:   "Backspace",
+	ESCAPE:      "Esc",
+	'\r':        "Enter",
+	'\t':        "Tab",
+	' ':         "Space",
+}
+
+// namedKeys are the keys for names, in lower case, including
+// some other names people use for them.
+var namedKeys = map[string]int{
+	"del":    DEL_KEY,
+	"pgup":   PAGE_UP,
+	"pgdn":   PAGE_DOWN,
+	"ins":    INSERT_KEY,
+	"bs":     BACKSPACE,
+	"escape": ESCAPE,
+	"return": '\r',
+}
+
+func init() {
+	for key, name := range keyNames {
+		namedKeys[strings.ToLower(name)] = key
+	}
+}
+
+var modNames = []struct {
+	mod  Modifier
+	name string
+}{
+	{Ctrl, "Ctrl-"},
+	{Alt, "Alt-"},
+	{Shift, "Shift-"},
+	{Meta, "Meta-"},
+}
+
+// KeyName returns the name of key code, as ReadKey returns it.
+func KeyName(code int) string {
+	ev := Decode(code)
+	name, ok := keyNames[ev.Key]
+	switch {
+	case ok:
+	case ev.Key == CTRL_SPACE:
+		name = "Space"
+		ev.Mod |= Ctrl
+	case ev.Key < ' ':
+		name = string(rune(ev.Key + '@'))
+		ev.Mod |= Ctrl
+	default:
+		name = string(rune(ev.Key))
+	}
+	var b strings.Builder
+	for _, m := range modNames {
+		if ev.Mod&m.mod != 0 {
+			b.WriteString(m.name)
+		}
+	}
+	b.WriteString(name)
+	return b.String()
+}
+
+// KeysName returns the name of a chord, a list of keys
+// to press one after the other.
+func KeysName(codes []int) string {
+	names := make([]string, len(codes))
+	for i, c := range codes {
+		names[i] = KeyName(c)
+	}
+	return strings.Join(names, " ")
+}
+
+// ParseKey returns the key code for the key called name.
+func ParseKey(name string) (int, error) {
+	s := name
+	var mod Modifier
+	for again := true; again; {
+		again = false
+		for _, m := range modNames {
+			if len(s) > len(m.name) && strings.EqualFold(s[:len(m.name)], m.name) {
+				mod |= m.mod
+				s = s[len(m.name):]
+				again = true
+			}
+		}
+	}
+
+	key, ok := namedKeys[strings.ToLower(s)]
+	if !ok {
+		r, size := utf8.DecodeRuneInString(s)
+		if size != len(s) || !unicode.IsPrint(r) {
+			return 0, fmt.Errorf("no key called %q", name)
+		}
+		key = int(r)
+	}
+
+	if mod&Ctrl != 0 && key < specialKey && key != BACKSPACE {
+		// Terminals send these as control characters.
+		switch c := unicode.ToUpper(rune(key)); {
+		case c == ' ':
+			key = CTRL_SPACE
+		case c >= '@' && c <= '_':
+			key = int(c) & 0x1f
+		default:
+			return 0, fmt.Errorf("terminals can't send %s", name)
+		}
+		mod &^= Ctrl
+	}
+	if mod&Shift != 0 && key < specialKey && key != '\t' {
+		if !unicode.IsLetter(rune(key)) {
+			return 0, fmt.Errorf("terminals can't send %s", name)
+		}
+		key = int(unicode.ToUpper(rune(key)))
+		mod &^= Shift
+	}
+	return Event{Key: key, Mod: mod}.Code(), nil
+}
+
+// ParseKeys returns the key codes for a chord, like
+// "Ctrl-X Ctrl-S", with spaces between the keys.
+func ParseKeys(names string) ([]int, error) {
+	fields := strings.Fields(names)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no keys in %q", names)
+	}
+	codes := make([]int, len(fields))
+	for i, f := range fields {
+		c, err := ParseKey(f)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = c
+	}
+	return codes, nil
+}
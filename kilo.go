@@ -5,8 +5,10 @@ import (
 	"io"
 	"os"
 
+	"GoKilo/config"
 	"GoKilo/editor"
 	"GoKilo/filemgt"
+	"GoKilo/keyboard"
 	"GoKilo/tty"
 )
 
@@ -20,27 +22,40 @@ func main() {
 		os.Exit(1)
 	}
 
-	E.Filename, err = filemgt.Open(os.Args, E.AppendRow)
-	if err != nil {
-		fmt.Printf("%s\n", err)
+	settings, cfgErr := config.Load(config.Path())
+	if err := E.Configure(settings); err != nil && cfgErr == nil {
+		cfgErr = err
+	}
+
+	targets := filemgt.ParseArgs(os.Args[1:])
+	for _, t := range targets {
+		if err = E.OpenFile(t.Filename); err != nil {
+			fmt.Printf("%s\n", err)
+		}
+		if t.Line > 0 {
+			E.GotoPosition(t.Line, t.Col)
+		}
+	}
+	if len(targets) == 0 {
+		E.NewBuffer("")
 	}
+	E.SwitchBuffer(0)
 
 	ttyDev := new(tty.Tty)
 	ttyDev.EnableRawMode()
 	defer ttyDev.DisableRawMode()
-	E.Dirty = false
-	E.UpdateAllSyntax()
+	keyboard.WatchResize()
 
-	E.SetStatusMessage("HELP: Ctrl-S = save | Ctrl-Q = quit | Ctrl-F = find")
+	E.SetStatusMessage("%s", E.KeyHelp())
+	if cfgErr != nil {
+		E.SetStatusMessage("%s", cfgErr)
+	}
 
-	for {
+	for again := E.CheckSwapFiles(); again; {
 		E.RefreshScreen()
-		again, e := E.ProcessKeypress()
-		if !again {
-			break
-		}
-		if e != nil {
-			fmt.Printf("%s\n", e)
+		again, err = E.ProcessKeypress()
+		if err != nil {
+			fmt.Printf("%s\n", err)
 			break
 		}
 	}
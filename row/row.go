@@ -1,50 +1,132 @@
 package row
 
 // Row instances represent a line of text in the file
-// under edit.
+// under edit. Chars and Render hold runes, so that a
+// multi-byte UTF-8 character is a single element.
 type Row struct {
 	Size          int
 	Rsize         int
-	Chars         []byte
-	Render        []byte
+	Chars         []rune
+	Render        []rune
 	Hl            []byte
 	HlOpenComment bool
+	Modified      bool
+	TabStop       int // columns between tab stops, from the buffer's settings
 }
 
-const kiloTabStop = 8
+// DefaultTabStop is the tab width for a Row with no TabStop set.
+const DefaultTabStop = 8
+
+func (row *Row) tabStop() int {
+	if row.TabStop > 0 {
+		return row.TabStop
+	}
+	return DefaultTabStop
+}
+
+// width returns the number of screen columns Chars[j] takes up,
+// counting a tab as 1 column.
+func (row *Row) width(j int) int {
+	if extends(row.Chars, j) {
+		return 0
+	}
+	if row.Chars[j] == '\t' {
+		return 1
+	}
+	return RuneWidth(row.Chars[j])
+}
+
+// RenderWidth returns the number of screen columns Render[j] takes up.
+func (row *Row) RenderWidth(j int) int {
+	if extends(row.Render, j) {
+		return 0
+	}
+	return RuneWidth(row.Render[j])
+}
 
 // RowCxToRx translates "in-file" position in the line to
 // rendered position in the line.
 func (row *Row) RowCxToRx(cx int) int {
-	rx := 0
+	rx, ts := 0, row.tabStop()
 	for j := 0; j < row.Size && j < cx; j++ {
 		if row.Chars[j] == '\t' {
-			rx += ((kiloTabStop - 1) - (rx % kiloTabStop))
+			rx += ((ts - 1) - (rx % ts))
 		}
-		rx++
+		rx += row.width(j)
 	}
 	return rx
 }
 
 // RowRxToCx translates rendered position in the line to
-// "in-file" position in the line.
+// "in-file" position in the line. The result is always
+// the start of a grapheme.
 func (row *Row) RowRxToCx(rx int) int {
-	curRx := 0
+	curRx, ts := 0, row.tabStop()
 	var cx int
-	for cx = 0; cx < row.Size; cx++ {
+	for cx = 0; cx < row.Size; {
+		next := row.NextGrapheme(cx)
 		if row.Chars[cx] == '\t' {
-			curRx += (kiloTabStop - 1) - (curRx % kiloTabStop)
+			curRx += (ts - 1) - (curRx % ts)
+		}
+		for j := cx; j < next; j++ {
+			curRx += row.width(j)
 		}
-		curRx++
 		if curRx > rx {
 			break
 		}
+		cx = next
+	}
+	return cx
+}
+
+// RenderIndex translates "in-file" position in the line to
+// an index into Render and Hl.
+func (row *Row) RenderIndex(cx int) int {
+	ri, rx, ts := 0, 0, row.tabStop()
+	for j := 0; j < row.Size && j < cx; j++ {
+		if row.Chars[j] == '\t' {
+			n := ts - (rx % ts)
+			ri += n
+			rx += n
+			continue
+		}
+		ri++
+		rx += row.width(j)
+	}
+	return ri
+}
+
+// NextGrapheme returns the position just past the grapheme
+// that starts at position cx.
+func (row *Row) NextGrapheme(cx int) int {
+	if cx >= row.Size {
+		return row.Size
+	}
+	cx++
+	for cx < row.Size && extends(row.Chars, cx) {
+		cx++
+	}
+	return cx
+}
+
+// PrevGrapheme returns the start of the grapheme just
+// before position cx.
+func (row *Row) PrevGrapheme(cx int) int {
+	if cx <= 0 {
+		return 0
+	}
+	if cx > row.Size {
+		cx = row.Size
+	}
+	cx--
+	for cx > 0 && extends(row.Chars, cx) {
+		cx--
 	}
 	return cx
 }
 
 // UpdateRow creates the "rendered" version of a row, which is the
-// bytes displayed on-screen.
+// runes displayed on-screen.
 func (row *Row) UpdateRow() {
 	tabs := 0
 	for _, c := range row.Chars {
@@ -53,20 +135,24 @@ func (row *Row) UpdateRow() {
 		}
 	}
 
-	row.Render = make([]byte, row.Size+tabs*(kiloTabStop-1))
+	ts := row.tabStop()
+	row.Render = make([]rune, row.Size+tabs*(ts-1))
 
-	idx := 0
-	for _, c := range row.Chars {
+	idx, rx := 0, 0
+	for j, c := range row.Chars {
 		if c == '\t' {
 			row.Render[idx] = ' '
 			idx++
-			for (idx % kiloTabStop) != 0 {
+			rx++
+			for (rx % ts) != 0 {
 				row.Render[idx] = ' '
 				idx++
+				rx++
 			}
 		} else {
 			row.Render[idx] = c
 			idx++
+			rx += row.width(j)
 		}
 	}
 	row.Rsize = idx
@@ -74,29 +160,29 @@ func (row *Row) UpdateRow() {
 	row.Hl = make([]byte, row.Rsize)
 }
 
-// RowInsertChar puts byte argument c into a line, position at
-func (row *Row) RowInsertChar(at int, c byte) {
+// RowInsertChar puts rune argument c into a line, position at
+func (row *Row) RowInsertChar(at int, c rune) {
 	switch {
 	case at < 0 || at > row.Size:
 		row.Chars = append(row.Chars, c)
 	case at == 0:
-		t := make([]byte, row.Size+1)
+		t := make([]rune, row.Size+1)
 		t[0] = c
 		copy(t[1:], row.Chars)
 		row.Chars = t
 	default:
 		row.Chars = append(
 			row.Chars[:at],
-			append(append(make([]byte, 0), c), row.Chars[at:]...)...,
+			append(append(make([]rune, 0), c), row.Chars[at:]...)...,
 		)
 	}
 	row.Size = len(row.Chars)
 	row.UpdateRow()
 }
 
-// RowDelChar deletes the byte at position at
+// RowDelChar deletes the rune at position at
 func (row *Row) RowDelChar(at int) {
-	if at < 0 || at > row.Size {
+	if at < 0 || at >= row.Size {
 		return
 	}
 	row.Chars = append(row.Chars[:at], row.Chars[at+1:]...)
@@ -104,9 +190,9 @@ func (row *Row) RowDelChar(at int) {
 	row.UpdateRow()
 }
 
-// RowAppendString adds an array-of-byte to the end of the in-memory
+// RowAppendString adds an array-of-rune to the end of the in-memory
 // representation of a text file.
-func (row *Row) RowAppendString(s []byte) {
+func (row *Row) RowAppendString(s []rune) {
 	row.Chars = append(row.Chars, s...)
 	row.Size = len(row.Chars)
 	row.UpdateRow()
@@ -0,0 +1,153 @@
+package row
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// Invalid UTF-8 bytes in a file get decoded to runes in the range
+// U+DC80 to U+DCFF, so that they can be written back out unchanged.
+const rawByteBase = 0xdc00
+
+// wide covers the East Asian Wide and Fullwidth characters, and the
+// emoji that terminals display two columns wide.
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115f, 1},
+		{0x231a, 0x231b, 1},
+		{0x2329, 0x232a, 1},
+		{0x23e9, 0x23ec, 1},
+		{0x23f0, 0x23f3, 3},
+		{0x25fd, 0x25fe, 1},
+		{0x2614, 0x2615, 1},
+		{0x2648, 0x2653, 1},
+		{0x267f, 0x2693, 20},
+		{0x26a1, 0x26aa, 9},
+		{0x26ab, 0x26bd, 18},
+		{0x26be, 0x26c4, 6},
+		{0x26c5, 0x26ce, 9},
+		{0x26d4, 0x26ea, 22},
+		{0x26f2, 0x26f3, 1},
+		{0x26f5, 0x26fa, 5},
+		{0x26fd, 0x2705, 8},
+		{0x270a, 0x270b, 1},
+		{0x2728, 0x274c, 36},
+		{0x274e, 0x2753, 5},
+		{0x2754, 0x2755, 1},
+		{0x2757, 0x2795, 62},
+		{0x2796, 0x2797, 1},
+		{0x27b0, 0x27bf, 15},
+		{0x2b1b, 0x2b1c, 1},
+		{0x2b50, 0x2b55, 5},
+		{0x2e80, 0x303e, 1},
+		{0x3041, 0x33ff, 1},
+		{0x3400, 0x4dbf, 1},
+		{0x4e00, 0x9fff, 1},
+		{0xa000, 0xa4cf, 1},
+		{0xa960, 0xa97f, 1},
+		{0xac00, 0xd7a3, 1},
+		{0xf900, 0xfaff, 1},
+		{0xfe10, 0xfe19, 1},
+		{0xfe30, 0xfe6f, 1},
+		{0xff00, 0xff60, 1},
+		{0xffe0, 0xffe6, 1},
+	},
+	R32: []unicode.Range32{
+		{0x16fe0, 0x16fe4, 1},
+		{0x17000, 0x18aff, 1},
+		{0x1b000, 0x1b16f, 1},
+		{0x1f004, 0x1f0cf, 203},
+		{0x1f18e, 0x1f191, 3},
+		{0x1f192, 0x1f19a, 1},
+		{0x1f200, 0x1f251, 1},
+		{0x1f300, 0x1f64f, 1},
+		{0x1f680, 0x1f6ff, 1},
+		{0x1f900, 0x1f9ff, 1},
+		{0x1fa70, 0x1faff, 1},
+		{0x20000, 0x2fffd, 1},
+		{0x30000, 0x3fffd, 1},
+	},
+}
+
+func isCombining(r rune) bool {
+	return r == zeroWidthJoiner || unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// extends reports whether s[i] belongs to the same grapheme
+// as s[i-1]: combining marks, and whatever follows a zero
+// width joiner.
+func extends(s []rune, i int) bool {
+	return i > 0 && (isCombining(s[i]) || s[i-1] == zeroWidthJoiner)
+}
+
+// RuneWidth returns the number of screen columns rune r occupies
+// on a terminal: 0 for combining marks, 2 for East Asian wide
+// characters, 1 for everything else.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x300:
+		return 1
+	case isCombining(r):
+		return 0
+	case unicode.Is(wide, r):
+		return 2
+	}
+	return 1
+}
+
+// Runes decodes a line of UTF-8 text. Bytes that aren't
+// valid UTF-8 turn into runes Bytes() can turn back into
+// the same bytes.
+func Runes(b []byte) []rune {
+	rs := make([]rune, 0, len(b))
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if r == utf8.RuneError && n == 1 {
+			r = rawByteBase + rune(b[0])
+		}
+		rs = append(rs, r)
+		b = b[n:]
+	}
+	return rs
+}
+
+// Bytes encodes a line of runes as UTF-8, the inverse of Runes().
+func Bytes(rs []rune) []byte {
+	b := make([]byte, 0, len(rs))
+	var buf [utf8.UTFMax]byte
+	for _, r := range rs {
+		if r >= rawByteBase+0x80 && r <= rawByteBase+0xff {
+			b = append(b, byte(r-rawByteBase))
+			continue
+		}
+		n := utf8.EncodeRune(buf[:], r)
+		b = append(b, buf[:n]...)
+	}
+	return b
+}
+
+// Index returns the index of the first instance of sep in s,
+// or -1 if sep isn't in s.
+func Index(s, sep []rune) int {
+	for i := 0; i+len(sep) <= len(s); i++ {
+		if HasPrefix(s[i:], sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasPrefix tests whether s begins with prefix.
+func HasPrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
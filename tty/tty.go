@@ -55,11 +55,14 @@ func (t *Tty) EnableRawMode() {
 	if e := tcSetAttr(os.Stdin.Fd(), &raw); e != nil {
 		log.Fatalf("Problem enabling raw mode: %s\n", e)
 	}
+	// Bracketed paste mode, so pasted text arrives marked as such.
+	os.Stdout.WriteString("\x1b[?2004h")
 }
 
 // DisableRawMode resets tty termios attributes to what they
 // were originally.
 func (t *Tty) DisableRawMode() {
+	os.Stdout.WriteString("\x1b[?2004l")
 	if e := tcSetAttr(os.Stdin.Fd(), t.original); e != nil {
 		log.Fatalf("Problem disabling raw mode: %s\n", e)
 	}